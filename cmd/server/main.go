@@ -3,19 +3,23 @@ package main
 import (
 	"collp-backend/config"
 	controller "collp-backend/controllers"
+	"collp-backend/mailer"
 	"collp-backend/middleware"
+	"collp-backend/ratelimit"
+	"collp-backend/repositories"
 	"collp-backend/routes"
-	"io/ioutil"
+	"collp-backend/services"
+	"collp-backend/utils"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 	"github.com/unrolled/secure"
 )
@@ -41,30 +45,27 @@ func SecurityMiddleware() gin.HandlerFunc {
 	}
 }
 
-var requestCounts = sync.Map{}
-var lastRequest = make(map[string]time.Time)
-
-func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-
-		if t, ok := lastRequest[ip]; ok {
-			if time.Since(t) > window {
-				requestCounts.Store(ip, 0)
-			}
+// trustedProxies อ่าน TRUSTED_PROXIES (IP หรือ CIDR คั่นด้วย ,) ไม่กำหนด = ไม่เชื่อ proxy ใดเลย
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
 		}
+	}
+	return proxies
+}
 
-		count, _ := requestCounts.LoadOrStore(ip, 0)
-		if count.(int) >= limit {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded",
-			})
-			return
+// reloadKeysOnSIGHUP โหลด signing keys ใหม่ทุกครั้งที่ได้รับ SIGHUP
+func reloadKeysOnSIGHUP(keys *utils.KeyManager) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		if err := keys.Reload(); err != nil {
+			log.Printf("Failed to reload signing keys, keeping current keys: %v", err)
+			continue
 		}
-
-		requestCounts.Store(ip, count.(int)+1)
-		lastRequest[ip] = time.Now()
-		c.Next()
+		log.Printf("Signing keys reloaded, active key %s", keys.ActiveKeyID())
 	}
 }
 
@@ -78,31 +79,65 @@ func main() {
 	// Initialize database
 	config.InitDB()
 
-	// Initialize Google OAuth
-	// Initialize auth controller
-	controller.InitAuthController()
+	// Token revocation store (Postgres + in-memory cache) ใช้ร่วมกันระหว่าง services และ middleware
+	revocations := services.NewTokenRevocationService(
+		repositories.NewTokenRevocationRepository(config.DB),
+		repositories.NewRefreshTokenRepository(config.DB),
+	)
+	middleware.SetRevocationChecker(revocations)
 
-	// Load RSA private key
-	keyData, err := ioutil.ReadFile("rsa.pem")
+	// Load RSA signing keys (JWT_KEYS_DIR หรือ rsa.pem ไฟล์เดียว)
+	keys, err := utils.LoadKeyManager(os.Getenv("JWT_KEYS_DIR"), "rsa.pem")
 	if err != nil {
-		log.Fatalf("Failed to read rsa.pem: %v", err)
+		log.Fatalf("Failed to load signing keys: %v", err)
 	}
+	log.Printf("Signing JWTs with key %s", keys.ActiveKeyID())
+
+	// Set key manager for middleware
+	middleware.SetKeyManager(keys)
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyData)
+	// Rotate keys without restart: kill -HUP <pid> หลังแก้ไฟล์ใน JWT_KEYS_DIR
+	go reloadKeysOnSIGHUP(keys)
+
+	// Mailer สำหรับ email ยืนยันบัญชี (MAILER=smtp|file|log)
+	mail, err := mailer.NewFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to parse RSA private key: %v", err)
+		log.Fatalf("Failed to initialize mailer: %v", err)
 	}
 
-	// Set public key for middleware
-	middleware.SetPublicKey(&privateKey.PublicKey)
+	// Initialize Google OAuth
+	// Initialize auth controller
+	controller.InitAuthController(config.DB, keys, revocations, mail)
+	controller.InitUserController(config.DB, revocations)
+	controller.InitOAuthClientController(config.DB, keys)
+
+	// Personal access tokens สำหรับ scripts/CI ใช้แทน JWT ใน Authorization header ได้
+	personalAccessTokens := services.NewPersonalAccessTokenService(
+		repositories.NewUserRepository(config.DB),
+		repositories.NewRoleRepository(config.DB),
+		repositories.NewPersonalAccessTokenRepository(config.DB),
+		repositories.NewMembershipRepository(config.DB),
+		services.NewAuditService(repositories.NewAuditLogRepository(config.DB)),
+	)
+	middleware.SetPersonalAccessTokenAuthenticator(personalAccessTokens)
+	controller.InitPersonalAccessTokenController(personalAccessTokens)
+
+	// อัพเดท last_seen_at ของ session จาก request ที่ใช้ JWT (ไม่เกินนาทีละครั้งต่อ session)
+	middleware.SetSessionActivityRecorder(services.NewSessionActivityRecorder(repositories.NewSessionRepository(config.DB)))
 
 	// Initialize Gin router
 	r := gin.Default()
 
+	// ใช้ X-Forwarded-For หา client IP เฉพาะเมื่อ request มาจาก proxy ที่เชื่อถือ
+	// ไม่อย่างนั้น client ปลอม header เพื่อหลบ rate limit และการล็อค IP ได้
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // Configure for production
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"*"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -112,11 +147,23 @@ func main() {
 	// Security middleware
 	r.Use(SecurityMiddleware())
 
-	// Rate limiting middleware
-	r.Use(RateLimitMiddleware(100, time.Minute))
+	// Client IP สำหรับ net/http handlers (login throttling, audit log)
+	r.Use(middleware.ClientIPMiddleware())
+
+	// Rate limiting (RATE_LIMIT_STORE=memory|redis, limits จาก RATE_LIMIT_*)
+	rateLimitStore, err := ratelimit.NewStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize rate limit store: %v", err)
+	}
+	rateLimits, err := ratelimit.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore)
+	r.Use(limiter.Middleware("global", rateLimits.Global, ratelimit.ByIP))
 
 	// Setup routes
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, limiter, rateLimits)
 
 	// Start server
 	port := os.Getenv("PORT")
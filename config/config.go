@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -47,16 +48,135 @@ func ConnectDatabase() *gorm.DB {
 	// Auto migrate the schema
 	err = db.AutoMigrate(
 		&models.User{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.UserTokenRevocation{},
+		&models.Permission{},
+		&models.Role{},
+		&models.LoginIPFailure{},
+		&models.AuditLog{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.WebAuthnCredential{},
+		&models.UserIdentity{},
+		&models.PersonalAccessToken{},
+		&models.OAuthClient{},
+		&models.Session{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
 		// Add other models here as needed
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
 
+	if err := seedRoles(db); err != nil {
+		log.Fatal("Failed to seed roles: ", err)
+	}
+
+	if err := migrateGoogleIdentities(db); err != nil {
+		log.Fatal("Failed to migrate Google identities: ", err)
+	}
+
+	if err := seedDefaultOrganization(db); err != nil {
+		log.Fatal("Failed to seed default organization: ", err)
+	}
+
 	log.Println("Database connected successfully")
 	return db
 }
 
+// seedRoles สร้าง roles และ permissions เริ่มต้น และตั้ง user ใน ADMIN_EMAILS เป็น admin
+func seedRoles(db *gorm.DB) error {
+	for roleName, permissionNames := range models.DefaultRolePermissions {
+		permissions := make([]models.Permission, 0, len(permissionNames))
+		for _, name := range permissionNames {
+			permission := models.Permission{Name: name}
+			if err := db.FirstOrCreate(&permission, models.Permission{Name: name}).Error; err != nil {
+				return fmt.Errorf("failed to seed permission %s: %w", name, err)
+			}
+			permissions = append(permissions, permission)
+		}
+
+		role := models.Role{Name: roleName}
+		err := db.Where(models.Role{Name: roleName}).
+			Attrs(models.Role{RequireMFA: models.DefaultMFARoles[roleName]}).
+			FirstOrCreate(&role).Error
+		if err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", roleName, err)
+		}
+		if len(permissions) > 0 {
+			if err := db.Model(&role).Association("Permissions").Append(permissions); err != nil {
+				return fmt.Errorf("failed to seed permissions of role %s: %w", roleName, err)
+			}
+		}
+	}
+
+	var adminEmails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			adminEmails = append(adminEmails, email)
+		}
+	}
+	if len(adminEmails) > 0 {
+		if err := db.Model(&models.User{}).Where("email IN ?", adminEmails).Update("role", models.RoleAdmin).Error; err != nil {
+			return fmt.Errorf("failed to promote admin users: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// migrateGoogleIdentities ย้าย users.google_id เดิมไปเป็น user_identities ของ provider google แล้วลบ column ทิ้ง
+func migrateGoogleIdentities(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.User{}, "google_id") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO user_identities (user_id, provider, subject, email, email_verified, created_at, updated_at)
+			SELECT id, 'google', google_id, email, email_verified_at IS NOT NULL, NOW(), NOW()
+			FROM users
+			WHERE google_id IS NOT NULL AND google_id <> '' AND deleted_at IS NULL
+			ON CONFLICT (provider, subject) DO NOTHING`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.User{}, "google_id")
+	})
+}
+
+// seedDefaultOrganization ครั้งแรกที่มี organizations ย้าย users เดิมทั้งหมดเข้า organization "default"
+// (admin เป็น owner ที่เหลือเป็น member) เพื่อให้รายการ users ที่จำกัดตาม organization ใช้ได้ต่อ
+func seedDefaultOrganization(db *gorm.DB) error {
+	var organizations int64
+	if err := db.Model(&models.Organization{}).Count(&organizations).Error; err != nil {
+		return err
+	}
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return err
+	}
+	if organizations > 0 || users == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		organization := &models.Organization{Name: "Default", Slug: "default"}
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			INSERT INTO memberships (organization_id, user_id, role, created_at, updated_at)
+			SELECT ?, id, CASE WHEN role = ? THEN ? ELSE ? END, NOW(), NOW()
+			FROM users
+			WHERE deleted_at IS NULL`,
+			organization.ID, models.RoleAdmin, models.OrgRoleOwner, models.OrgRoleMember).Error
+	})
+}
+
 var DB *gorm.DB
 
 func InitDB() {
@@ -1,24 +1,73 @@
 package controllers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
+	"collp-backend/mailer"
+	"collp-backend/middleware"
+	"collp-backend/oidc"
+	"collp-backend/repositories"
 	"collp-backend/services"
+	"collp-backend/utils"
+	"collp-backend/validators"
+
+	"gorm.io/gorm"
 )
 
 var authService services.AuthServiceInterface
+var emailVerificationService services.EmailVerificationService
+var passwordResetService services.PasswordResetService
+
+// signingKeys key manager สำหรับเผยแพร่ JWKS
+var signingKeys *utils.KeyManager
 
 // InitAuthController initialize auth service
-func InitAuthController() {
-	authService = services.NewAuthService()
-	if err := authService.InitGoogleOauth(); err != nil {
-		log.Fatalf("Failed to initialize auth service: %v", err)
+func InitAuthController(db *gorm.DB, keys *utils.KeyManager, revocations services.TokenRevocationService, mail mailer.Mailer) {
+	signingKeys = keys
+	userRepo := repositories.NewUserRepository(db)
+	refreshRepo := repositories.NewRefreshTokenRepository(db)
+	audit := services.NewAuditService(repositories.NewAuditLogRepository(db))
+
+	throttlePolicy, err := services.LoadLoginThrottlePolicy()
+	if err != nil {
+		log.Fatalf("Invalid login throttle configuration: %v", err)
+	}
+	throttle := services.NewLoginThrottleService(throttlePolicy, userRepo, repositories.NewLoginFailureRepository(db), audit)
+
+	roleRepo := repositories.NewRoleRepository(db)
+	identityRepo := repositories.NewUserIdentityRepository(db)
+	credentialRepo := repositories.NewWebAuthnCredentialRepository(db)
+	mfaService = services.NewMFAService(userRepo, roleRepo, repositories.NewRecoveryCodeRepository(db), audit, os.Getenv("MFA_ISSUER"))
+	passkeyService, err = services.NewWebAuthnService(services.LoadWebAuthnConfig(), userRepo, credentialRepo, identityRepo, audit)
+	if err != nil {
+		log.Fatalf("Failed to initialize passkey service: %v", err)
+	}
+	providers, err := oidc.NewRegistryFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load OIDC providers: %v", err)
 	}
+	identityService = services.NewIdentityService(userRepo, identityRepo, credentialRepo, audit)
+	membershipRepo := repositories.NewMembershipRepository(db)
+	userSvc := services.NewUserService(userRepo, roleRepo, identityRepo, membershipRepo, revocations, audit)
+	organizationRepo := repositories.NewOrganizationRepository(db)
+	organizationService = services.NewOrganizationService(userRepo, organizationRepo, membershipRepo, revocations, audit)
+	impersonationService = services.NewImpersonationService(keys, userRepo, roleRepo, membershipRepo, revocations, audit)
+	sessionRepo := repositories.NewSessionRepository(db)
+	sessionService = services.NewSessionService(sessionRepo, refreshRepo, revocations, audit)
+	invitationService = services.NewInvitationService(userRepo, organizationRepo, membershipRepo, repositories.NewInvitationRepository(db), audit, mail, os.Getenv("INVITATION_URL"))
+	authService = services.NewAuthService(providers, keys, userRepo, userSvc, refreshRepo, sessionRepo, membershipRepo, revocations, throttle, mfaService, passkeyService, identityService, invitationService)
+
+	userTokenRepo := repositories.NewUserTokenRepository(db)
+	emailVerificationService = services.NewEmailVerificationService(userRepo, userTokenRepo, mail, services.LoadEmailVerificationSecret(), os.Getenv("EMAIL_VERIFICATION_URL"))
+	passwordResetService = services.NewPasswordResetService(userRepo, userTokenRepo, repositories.NewPersonalAccessTokenRepository(db), repositories.NewOAuthClientRepository(db), revocations, audit, mail, os.Getenv("PASSWORD_RESET_URL"))
 }
 
 func writeJSONError(w http.ResponseWriter, status int, message string) {
@@ -27,36 +76,447 @@ func writeJSONError(w http.ResponseWriter, status int, message string) {
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
-// GoogleLogin redirect ผู้ใช้ไปหน้า Google Login
-func GoogleLogin(w http.ResponseWriter, r *http.Request) {
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// clientInfo อุปกรณ์ที่ส่ง request สำหรับบันทึกใน session
+func clientInfo(r *http.Request) services.ClientInfo {
+	return services.ClientInfo{IP: middleware.ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// oauthStateCookie ผูก state ของแต่ละ login เข้ากับ browser ที่เริ่ม login
+const oauthStateCookie = "oauth_state"
+
+// secureCookies ใช้ COOKIE_SECURE=true เมื่อรันหลัง HTTPS
+func secureCookies() bool {
+	return os.Getenv("COOKIE_SECURE") == "true"
+}
+
+// GetOAuthProviders รายการ OIDC providers ที่ใช้ login ได้
+func GetOAuthProviders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    authService.GetOAuthProviders(),
+	})
+}
+
+// OAuthLogin redirect ผู้ใช้ไปหน้า login ของ provider ตาม path /api/auth/{provider}/login
+// ?invitation= token คำเชิญเข้า organization ที่จะตอบรับหลัง login สำเร็จ
+func OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	state := utils.GenerateRandomString(32)
+
 	// ใช้ service เพื่อสร้าง auth URL
-	url := authService.GetGoogleAuthURL("random-state-string")
+	url, err := authService.GetOAuthLoginURL(r.Context(), r.PathValue("provider"), state, r.URL.Query().Get("invitation"))
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInvitation) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("OAuth login error: %v", err)
+		writeJSONError(w, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	setOAuthStateCookie(w, state)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-// GoogleCallback รับ code จาก Google แล้วแลก token + ดึง user info
-func GoogleCallback(w http.ResponseWriter, r *http.Request) {
+// setOAuthStateCookie ผูก state กับ browser ที่เริ่ม flow เพื่อตรวจสอบตอน callback
+func setOAuthStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/api/auth",
+		MaxAge:   int(services.OAuthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OAuthCallback รับ code จาก provider แล้วแลก token + ตรวจสอบ id_token
+func OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	// รับ parameters จาก request
 	state := r.FormValue("state")
 	code := r.FormValue("code")
 
+	// state ต้องตรงกับ cookie ของ browser ที่เริ่ม login
+	cookie, err := r.Cookie(oauthStateCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/api/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		writeJSONError(w, http.StatusBadRequest, services.ErrInvalidOAuthState.Error())
+		return
+	}
+
+	// provider ส่ง error กลับมาแทน code เช่น user กดยกเลิก (ไม่สะท้อนค่าจาก query กลับไปใน response)
+	if providerErr := r.FormValue("error"); providerErr != "" {
+		log.Printf("OAuth provider %q returned error: %q", r.PathValue("provider"), providerErr)
+		writeJSONError(w, http.StatusBadRequest, "Authorization failed")
+		return
+	}
+
 	// เรียกใช้ service เพื่อ handle callback
-	userInfo, err := authService.HandleGoogleCallback(code, state)
+	result, err := authService.HandleOAuthCallback(r.Context(), r.PathValue("provider"), code, state, clientInfo(r))
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+		writeOAuthCallbackError(w, err)
 		return
 	}
 
-	// Convert userInfo to map สำหรับ query string
-	values := url.Values{}
-	values.Set("email", userInfo.Email)
-	values.Set("name", userInfo.Name)
-	values.Set("picture", userInfo.Picture)
-	values.Set("verified_email", fmt.Sprintf("%v", userInfo.VerifiedEmail))
-	values.Set("token", userInfo.Token)
-	values.Set("token_expiry", fmt.Sprintf("%d", userInfo.TokenExpiry))
+	frontendRedirectURL := os.Getenv("FRONTEND_REDIRECT")
+
+	// ผูก provider สำเร็จ: user ยัง login อยู่ด้วย token เดิม ไม่ต้องส่ง token ใหม่
+	if result.LinkedProvider != "" {
+		values := url.Values{}
+		values.Set("linked", result.LinkedProvider)
+		http.Redirect(w, r, fmt.Sprintf("%s?%s", frontendRedirectURL, values.Encode()), http.StatusSeeOther)
+		return
+	}
+
+	// ห้ามใส่ JWT ใน URL: ส่งผ่าน HttpOnly cookie หรือ authorization code แบบใช้ครั้งเดียว
+	// ถ้าต้องยืนยัน 2FA ส่ง mfa_token ผ่าน code เสมอ (ยังไม่มี JWT ให้ใส่ cookie)
+	if tokenDeliveryMode() == tokenDeliveryCookie && !result.IsMFAChallenge() {
+		setAuthCookies(w, result)
+	} else {
+		values := url.Values{}
+		values.Set("code", authService.CreateAuthCode(result))
+		frontendRedirectURL = fmt.Sprintf("%s?%s", frontendRedirectURL, values.Encode())
+	}
 
-	// Redirect ไป frontend พร้อม query string
-	frontendRedirectURL := fmt.Sprintf("%s?%s", os.Getenv("FRONTEND_REDIRECT"), values.Encode())
 	http.Redirect(w, r, frontendRedirectURL, http.StatusSeeOther)
 }
+
+// writeOAuthCallbackError แปลง error ของ callback เป็นข้อความคงที่
+// error อื่นอาจมีรายละเอียดจาก provider หรือ DB จึงไม่ส่งให้ client
+func writeOAuthCallbackError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		writeJSONError(w, http.StatusNotFound, oidc.ErrUnknownProvider.Error())
+	case errors.Is(err, services.ErrInvalidOAuthState):
+		writeJSONError(w, http.StatusBadRequest, services.ErrInvalidOAuthState.Error())
+	case errors.Is(err, oidc.ErrCodeExchange):
+		writeJSONError(w, http.StatusBadRequest, "Invalid or expired authorization code")
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		writeJSONError(w, http.StatusBadRequest, "Invalid ID token")
+	case errors.Is(err, services.ErrUserInactive):
+		writeJSONError(w, http.StatusForbidden, services.ErrUserInactive.Error())
+	case errors.Is(err, services.ErrIdentityAlreadyLinked):
+		writeJSONError(w, http.StatusConflict, services.ErrIdentityAlreadyLinked.Error())
+	case errors.Is(err, services.ErrIdentityLinkRequired):
+		writeJSONError(w, http.StatusConflict, services.ErrIdentityLinkRequired.Error())
+	case errors.Is(err, services.ErrIdentityProviderUnavailable):
+		log.Printf("OAuth callback error: %v", err)
+		writeJSONError(w, http.StatusBadGateway, "Identity provider is unavailable")
+	default:
+		log.Printf("OAuth callback error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Login failed")
+	}
+}
+
+// ExchangeAuthCode แลก authorization code จาก OAuthCallback เป็น JWT
+func ExchangeAuthCode(w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	result, err := authService.ExchangeAuthCode(reqBody.Code)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+const (
+	tokenDeliveryCode   = "code"
+	tokenDeliveryCookie = "cookie"
+)
+
+// tokenDeliveryMode วิธีส่ง JWT ให้ frontend หลัง OAuth (AUTH_TOKEN_DELIVERY=code|cookie)
+func tokenDeliveryMode() string {
+	if os.Getenv("AUTH_TOKEN_DELIVERY") == tokenDeliveryCookie {
+		return tokenDeliveryCookie
+	}
+	return tokenDeliveryCode
+}
+
+// refreshTokenCookie ชื่อ HttpOnly cookie ของ refresh token (ส่งเฉพาะ /api/auth)
+const refreshTokenCookie = "refresh_token"
+
+// setAuthCookies ส่ง JWT และ refresh token ผ่าน HttpOnly cookie ที่ AuthMiddleware อ่านได้
+func setAuthCookies(w http.ResponseWriter, result *services.AuthResult) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.AccessTokenCookie,
+		Value:    result.Token,
+		Path:     "/",
+		Expires:  time.Unix(result.TokenExpiry, 0),
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    result.RefreshToken,
+		Path:     "/api/auth",
+		Expires:  time.Unix(result.RefreshTokenExpiry, 0),
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// RefreshToken แลก refresh token เป็น access token + refresh token ชุดใหม่
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+	}
+
+	// โหมด cookie: ไม่มี refresh_token ใน body ให้อ่านจาก cookie แทน
+	fromCookie := false
+	if reqBody.RefreshToken == "" {
+		if cookie, err := r.Cookie(refreshTokenCookie); err == nil {
+			reqBody.RefreshToken = cookie.Value
+			fromCookie = true
+		}
+	}
+
+	result, err := authService.RefreshTokens(reqBody.RefreshToken, clientInfo(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken), errors.Is(err, services.ErrRefreshTokenReuse):
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, services.ErrUserInactive):
+			writeJSONError(w, http.StatusForbidden, err.Error())
+		default:
+			log.Printf("Token refresh failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "Token refresh failed")
+		}
+		return
+	}
+
+	if fromCookie {
+		setAuthCookies(w, result)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// clearAuthCookies ลบ cookie ของ access token และ refresh token
+func clearAuthCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.AccessTokenCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Path:     "/api/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Logout ออกจากระบบ: เพิกถอน access token ปัจจุบันและ refresh token ที่ส่งมา
+func Logout(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := middleware.ExtractToken(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var reqBody struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+	}
+	if reqBody.RefreshToken == "" {
+		if cookie, err := r.Cookie(refreshTokenCookie); err == nil {
+			reqBody.RefreshToken = cookie.Value
+		}
+	}
+
+	if err := authService.Logout(accessToken, reqBody.RefreshToken); err != nil {
+		if errors.Is(err, services.ErrInvalidAccessToken) {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		log.Printf("Logout failed: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Logout failed")
+		return
+	}
+
+	clearAuthCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll ออกจากระบบทุกอุปกรณ์ของ user
+func LogoutAll(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := middleware.ExtractToken(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := authService.LogoutAll(accessToken); err != nil {
+		if errors.Is(err, services.ErrInvalidAccessToken) {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrLogoutAllNotAllowed) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		log.Printf("Logout all devices failed: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Logout failed")
+		return
+	}
+
+	clearAuthCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail ยืนยัน email ด้วย token จากลิงก์ใน email
+// token เดิมของ user ยังมี email_verified=false ให้เรียก /api/auth/refresh เพื่อรับ token ใหม่
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	user, err := emailVerificationService.VerifyEmail(reqBody.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Email verification failed: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Email verification failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    user,
+	})
+}
+
+// ResendVerificationEmail ส่งลิงก์ยืนยัน email ให้ user ที่ login อยู่อีกครั้ง
+func ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := emailVerificationService.ResendVerification(claims.UserID); err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Printf("Failed to resend verification email: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Verification email sent",
+	})
+}
+
+// ForgotPassword ส่งลิงก์ reset password ทาง email
+// ตอบเหมือนกันทุกกรณีเพื่อไม่ให้รู้ว่ามี email นี้ในระบบหรือไม่
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req validators.PasswordForgotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := validators.ValidatePasswordForgot(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := passwordResetService.RequestReset(req.Email); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"message": "If an account with that email exists, a password reset link has been sent",
+	})
+}
+
+// ResetPassword ตั้ง password ใหม่ด้วย token จากลิงก์ใน email แล้วออกจากระบบทุกอุปกรณ์
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req validators.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := validators.ValidatePasswordReset(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := passwordResetService.ResetPassword(req.Token, req.Password, middleware.ClientIP(r)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResetToken), errors.Is(err, services.ErrWeakPassword):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("Password reset failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "Password reset failed")
+		}
+		return
+	}
+
+	clearAuthCookies(w)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Password has been reset, please sign in again",
+	})
+}
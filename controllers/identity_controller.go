@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/oidc"
+	"collp-backend/repositories"
+	"collp-backend/services"
+	"collp-backend/utils"
+)
+
+var identityService services.IdentityService
+
+// writeIdentityError แปลง error ของการผูกบัญชีเป็น HTTP status
+func writeIdentityError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repositories.ErrUserIdentityNotFound):
+		writeJSONError(w, http.StatusNotFound, "Identity not found")
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrLastLoginMethod):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Identity error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// GetIdentities ดึง providers ที่ผูกกับ user ปัจจุบัน
+func GetIdentities(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	identities, err := identityService.ListIdentities(claims.UserID)
+	if err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    identities,
+	})
+}
+
+// LinkIdentity เริ่มผูก provider กับ user ปัจจุบัน คืน URL ให้ frontend redirect ไป provider
+// callback จะกลับมาที่ /api/auth/{provider}/callback เหมือน login ปกติ
+func LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	state := utils.GenerateRandomString(32)
+	url, err := authService.GetOAuthLinkURL(r.Context(), r.PathValue("provider"), state, claims.UserID)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			writeIdentityError(w, err)
+			return
+		}
+		log.Printf("OAuth link error: %v", err)
+		writeJSONError(w, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	setOAuthStateCookie(w, state)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    map[string]string{"url": url},
+	})
+}
+
+// UnlinkIdentity ยกเลิกการผูก provider ของ user ปัจจุบัน
+func UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Invalid identity ID format")
+		return
+	}
+
+	if err := identityService.UnlinkIdentity(claims.UserID, uint(id)); err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var impersonationService services.ImpersonationService
+
+// writeImpersonationError แปลง error ของการ impersonate เป็น HTTP status
+func writeImpersonationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrImpersonateSelf), errors.Is(err, services.ErrNotImpersonating):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrImpersonationNotAllowed), errors.Is(err, services.ErrUserInactive),
+		errors.Is(err, services.ErrNoActiveOrganization):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	default:
+		log.Printf("Impersonation error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// StartImpersonation ออก token อายุสั้นให้ admin ดูระบบในฐานะ user :id
+func StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	id, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// reason ไม่บังคับ ถ้ามีจะถูกบันทึกใน audit log
+	var reqBody struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil && !errors.Is(err, io.EOF) {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	result, err := impersonationService.StartImpersonation(claims.UserID, claims.OrgID, id, reqBody.Reason, middleware.ClientIP(r))
+	if err != nil {
+		writeImpersonationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// StopImpersonation เพิกถอน token ของการ impersonate ที่ใช้อยู่
+func StopImpersonation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := impersonationService.StopImpersonation(claims, middleware.ClientIP(r)); err != nil {
+		writeImpersonationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Impersonation stopped",
+	})
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var invitationService services.InvitationService
+
+// writeInvitationError แปลง error ของคำเชิญเป็น HTTP status
+func writeInvitationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput), errors.Is(err, services.ErrInvalidInvitation):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvitationEmailMismatch):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repositories.ErrInvitationNotFound):
+		writeJSONError(w, http.StatusNotFound, "Invitation not found")
+	case errors.Is(err, services.ErrInvitationExists), errors.Is(err, services.ErrInvitationNotPending):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		writeOrganizationError(w, err)
+	}
+}
+
+// parseInvitationID อ่าน invitation ID จาก path parameter
+func parseInvitationID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("invitation_id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("Invalid invitation ID format")
+	}
+	return uint(id), nil
+}
+
+// GetOrganizationInvitations ดึงคำเชิญทั้งหมดของ organization (owner/admin ของ organization)
+func GetOrganizationInvitations(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitations, err := invitationService.ListInvitations(claims.UserID, organizationID)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    invitations,
+	})
+}
+
+// CreateOrganizationInvitation เชิญ email เข้า organization ด้วย role ที่กำหนด
+func CreateOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Email == "" {
+		writeJSONError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	invitation, err := invitationService.CreateInvitation(claims.UserID, organizationID, req.Email, req.Role)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    invitation,
+	})
+}
+
+// ResendOrganizationInvitation ส่งคำเชิญอีกครั้งด้วยลิงก์ใหม่
+func ResendOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	invitationID, err := parseInvitationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitation, err := invitationService.ResendInvitation(claims.UserID, organizationID, invitationID)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    invitation,
+	})
+}
+
+// RevokeOrganizationInvitation ยกเลิกคำเชิญที่ยังรอตอบรับ
+func RevokeOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	invitationID, err := parseInvitationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := invitationService.RevokeInvitation(claims.UserID, organizationID, invitationID); err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LookupInvitation แสดง organization และ email ของคำเชิญจาก token ในลิงก์ ก่อนให้ user สมัครหรือ login
+func LookupInvitation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	invitation, err := invitationService.GetInvitation(req.Token)
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidInvitation) {
+			log.Printf("Invitation lookup failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    invitation,
+	})
+}
+
+// AcceptInvitation ตอบรับคำเชิญด้วยบัญชีที่ login อยู่ (email ต้องตรงกับที่ถูกเชิญ)
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	user, err := userService.GetUserByID(claims.UserID)
+	if err != nil {
+		writeUserServiceError(w, err)
+		return
+	}
+
+	invitation, err := invitationService.AcceptInvitation(req.Token, user)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Invitation accepted, switch organization to start using it",
+		"data":    invitation,
+	})
+}
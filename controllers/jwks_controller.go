@@ -0,0 +1,11 @@
+package controllers
+
+import (
+	"net/http"
+)
+
+// JWKS เผยแพร่ public keys สำหรับให้ service อื่นตรวจสอบ JWT ของ CollP
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, signingKeys.JWKS())
+}
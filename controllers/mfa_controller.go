@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var mfaService services.MFAService
+
+// writeMFAError แปลง error ของ 2FA เป็น HTTP status
+func writeMFAError(w http.ResponseWriter, err error) {
+	var throttled *services.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		writeJSONError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrInvalidMFAToken):
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrInvalidMFACode):
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrUserInactive), errors.Is(err, services.ErrMFARequiredByRole):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnabled),
+		errors.Is(err, services.ErrMFAEnrollmentNotStarted), errors.Is(err, services.ErrMFAEnrollmentRequired):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, repositories.ErrRoleNotFound):
+		writeJSONError(w, http.StatusNotFound, "Role not found")
+	default:
+		log.Printf("Two-factor authentication error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// mfaRequest body ของ endpoints 2FA
+type mfaRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func decodeMFARequest(w http.ResponseWriter, r *http.Request) (*mfaRequest, bool) {
+	var req mfaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return nil, false
+	}
+	return &req, true
+}
+
+// writeAuthResult ส่ง JWT ที่ได้หลังยืนยัน 2FA (โหมด cookie จะ set cookie ด้วย)
+func writeAuthResult(w http.ResponseWriter, result *services.AuthResult) {
+	if tokenDeliveryMode() == tokenDeliveryCookie {
+		setAuthCookies(w, result)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// VerifyMFA ยืนยัน TOTP code หรือรหัสสำรองหลัง login ได้ mfa_token
+func VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMFARequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := authService.VerifyMFA(req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeAuthResult(w, result)
+}
+
+// BeginMFAEnrollmentChallenge เริ่มลงทะเบียน TOTP ด้วย mfa_token เมื่อ role บังคับ 2FA
+func BeginMFAEnrollmentChallenge(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMFARequest(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := authService.BeginMFAEnrollment(req.MFAToken)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    enrollment,
+	})
+}
+
+// ConfirmMFAEnrollmentChallenge ยืนยันการลงทะเบียน TOTP ด้วย mfa_token แล้วรับ JWT และรหัสสำรอง
+func ConfirmMFAEnrollmentChallenge(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMFARequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := authService.ConfirmMFAEnrollment(req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeAuthResult(w, result)
+}
+
+// GetMFAStatus ดึงสถานะ 2FA ของ user ปัจจุบัน
+func GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status, err := mfaService.GetStatus(claims.UserID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// BeginTOTPEnrollment สร้าง TOTP secret, otpauth URI และ QR code ให้ user ปัจจุบัน
+func BeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	enrollment, err := mfaService.BeginTOTPEnrollment(claims.UserID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    enrollment,
+	})
+}
+
+// ConfirmTOTPEnrollment เปิดใช้ 2FA ด้วย code จาก authenticator แล้วคืนรหัสสำรอง (แสดงครั้งเดียว)
+func ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	req, ok := decodeMFARequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := mfaService.ConfirmTOTPEnrollment(claims.UserID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTOTP ปิด 2FA ของ user ปัจจุบัน (ต้องใส่ code)
+func DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	req, ok := decodeMFARequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := mfaService.DisableTOTP(claims.UserID, req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes ออกรหัสสำรองชุดใหม่ให้ user ปัจจุบัน (ต้องใส่ code)
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	req, ok := decodeMFARequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := mfaService.RegenerateRecoveryCodes(claims.UserID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// SetRoleMFARequirement กำหนดว่า role บังคับ 2FA หรือไม่
+func SetRoleMFARequirement(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var reqBody struct {
+		RequireMFA *bool `json:"require_mfa"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if reqBody.RequireMFA == nil {
+		writeJSONError(w, http.StatusBadRequest, "require_mfa is required")
+		return
+	}
+
+	if err := mfaService.SetRoleRequirement(r.PathValue("name"), *reqBody.RequireMFA, claims.UserID); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Role MFA policy updated successfully",
+	})
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+	"collp-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+var oauthClientService services.OAuthClientService
+
+// InitOAuthClientController initialize OAuth client service
+func InitOAuthClientController(db *gorm.DB, keys *utils.KeyManager) {
+	oauthClientService = services.NewOAuthClientService(
+		keys,
+		repositories.NewUserRepository(db),
+		repositories.NewRoleRepository(db),
+		repositories.NewMembershipRepository(db),
+		repositories.NewOAuthClientRepository(db),
+		services.NewAuditService(repositories.NewAuditLogRepository(db)),
+	)
+}
+
+// writeOAuthTokenError ตอบ error ตามรูปแบบของ RFC 6749 section 5.2
+func writeOAuthTokenError(w http.ResponseWriter, status int, code, description string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="collp"`)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// clientCredentials อ่าน client_id/client_secret จาก HTTP Basic หรือจาก form body (อย่างใดอย่างหนึ่ง)
+func clientCredentials(r *http.Request) (string, string, bool) {
+	formID, formSecret := r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	basicID, basicSecret, hasBasic := r.BasicAuth()
+	if !hasBasic {
+		return formID, formSecret, true
+	}
+	if formSecret != "" {
+		return "", "", false
+	}
+
+	// RFC 6749 section 2.3.1: ค่าใน Basic ถูก form-urlencode ก่อน
+	clientID, err := url.QueryUnescape(basicID)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err := url.QueryUnescape(basicSecret)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, clientSecret, true
+}
+
+// OAuthToken token endpoint ของ client_credentials grant สำหรับ service-to-service
+func OAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthTokenError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+	if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+		writeOAuthTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "Only client_credentials is supported")
+		return
+	}
+
+	clientID, clientSecret, ok := clientCredentials(r)
+	if !ok {
+		writeOAuthTokenError(w, http.StatusBadRequest, "invalid_request", "Use exactly one client authentication method")
+		return
+	}
+
+	token, err := oauthClientService.IssueToken(clientID, clientSecret, r.PostForm.Get("scope"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidClient):
+			writeOAuthTokenError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+		case errors.Is(err, services.ErrInvalidClientScope):
+			writeOAuthTokenError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		default:
+			log.Printf("OAuth token error: %v", err)
+			writeOAuthTokenError(w, http.StatusInternalServerError, "server_error", "Internal server error")
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	writeJSON(w, http.StatusOK, token)
+}
+
+// writeOAuthClientError แปลง error ของการจัดการ OAuth clients เป็น HTTP status
+func writeOAuthClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidClientName), errors.Is(err, services.ErrInvalidTokenScope):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrOAuthClientNotFound):
+		writeJSONError(w, http.StatusNotFound, "OAuth client not found")
+	default:
+		log.Printf("OAuth client error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// parseOAuthClientID อ่าน ID ของ client จาก path parameter :id
+func parseOAuthClientID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("Invalid client ID format")
+	}
+	return uint(id), nil
+}
+
+// GetOAuthClients รายการ OAuth clients
+func GetOAuthClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := oauthClientService.ListClients()
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    clients,
+	})
+}
+
+// CreateOAuthClient ลงทะเบียน client ใหม่ คืน client_secret ครั้งเดียว
+func CreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	client, err := oauthClientService.CreateClient(claims.UserID, claims.OrgID, req.Name, req.Scopes)
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    client,
+	})
+}
+
+// RotateOAuthClientSecret ออก client_secret ใหม่ให้ client
+func RotateOAuthClientSecret(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := parseOAuthClientID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client, err := oauthClientService.RotateSecret(claims.UserID, id)
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    client,
+	})
+}
+
+// DeleteOAuthClient ลบ client
+func DeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := parseOAuthClientID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := oauthClientService.DeleteClient(claims.UserID, id); err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
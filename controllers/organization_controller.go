@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var organizationService services.OrganizationService
+
+// writeOrganizationError แปลง error ของ organization เป็น HTTP status
+func writeOrganizationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidOrganization), errors.Is(err, services.ErrInvalidOrgRole):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrSessionNotFound):
+		writeJSONError(w, http.StatusUnauthorized, "Session has ended, sign in again")
+	case errors.Is(err, services.ErrNotOrganizationMember),
+		errors.Is(err, services.ErrOrganizationForbidden),
+		errors.Is(err, services.ErrOwnerRoleRequired),
+		errors.Is(err, services.ErrUserInactive):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repositories.ErrMembershipNotFound):
+		writeJSONError(w, http.StatusNotFound, "Member not found")
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrOrganizationExists),
+		errors.Is(err, services.ErrAlreadyMember),
+		errors.Is(err, services.ErrLastOwner):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Organization error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// parseOrganizationID อ่าน organization ID จาก path parameter
+func parseOrganizationID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("Invalid organization ID format")
+	}
+	return uint(id), nil
+}
+
+// parseMemberUserID อ่าน user ID ของสมาชิกจาก path parameter
+func parseMemberUserID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("user_id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("Invalid user ID format")
+	}
+	return uint(id), nil
+}
+
+// GetMyOrganizations ดึง organizations ที่ user ปัจจุบันเป็นสมาชิกพร้อม role
+func GetMyOrganizations(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	memberships, err := organizationService.ListMemberships(claims.UserID)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"organizations":          memberships,
+			"active_organization_id": claims.OrgID,
+		},
+	})
+}
+
+// CreateOrganization สร้าง organization ใหม่ ผู้สร้างเป็น owner
+func CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	organization, err := organizationService.CreateOrganization(claims.UserID, req.Name, req.Slug)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    organization,
+	})
+}
+
+// GetOrganizationMembers ดึงสมาชิกของ organization (เฉพาะสมาชิก)
+func GetOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	members, err := organizationService.ListMembers(claims.UserID, organizationID)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    members,
+	})
+}
+
+// AddOrganizationMember เพิ่ม user ที่มีบัญชีแล้วเข้า organization (owner/admin ของ organization)
+func AddOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Email == "" {
+		writeJSONError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	membership, err := organizationService.AddMember(claims.UserID, organizationID, req.Email, req.Role)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    membership,
+	})
+}
+
+// UpdateOrganizationMemberRole เปลี่ยน role ของสมาชิกใน organization
+func UpdateOrganizationMemberRole(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := parseMemberUserID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := organizationService.UpdateMemberRole(claims.UserID, organizationID, userID, req.Role); err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Member role updated successfully",
+	})
+}
+
+// RemoveOrganizationMember นำสมาชิกออกจาก organization หรือออกจาก organization เอง
+func RemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	organizationID, err := parseOrganizationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := parseMemberUserID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := organizationService.RemoveMember(claims.UserID, organizationID, userID); err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SwitchOrganization เปลี่ยน organization ที่ใช้งานอยู่ คืน token คู่ใหม่ที่มี org_id ใหม่
+func SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		OrganizationID uint `json:"organization_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.OrganizationID == 0 {
+		writeJSONError(w, http.StatusBadRequest, "organization_id is required")
+		return
+	}
+
+	result, err := authService.SwitchOrganization(claims, req.OrganizationID)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	writeAuthResult(w, result)
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var passkeyService services.WebAuthnService
+
+// writePasskeyError แปลง error ของ passkey เป็น HTTP status
+func writePasskeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPasskeySession):
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrPasskeyVerificationFailed):
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrUserInactive), errors.Is(err, services.ErrPasskeyCloned):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrLastLoginMethod):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, repositories.ErrWebAuthnCredentialNotFound):
+		writeJSONError(w, http.StatusNotFound, "Passkey not found")
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	default:
+		log.Printf("Passkey error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// passkeyRequest body ของขั้น finish: credential คือผลจาก navigator.credentials.create/get
+type passkeyRequest struct {
+	SessionToken string          `json:"session_token"`
+	Name         string          `json:"name"`
+	Credential   json.RawMessage `json:"credential"`
+}
+
+func decodePasskeyRequest(w http.ResponseWriter, r *http.Request) (*passkeyRequest, bool) {
+	var req passkeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return nil, false
+	}
+	if len(req.Credential) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "credential is required")
+		return nil, false
+	}
+	return &req, true
+}
+
+// BeginPasskeyLogin สร้าง options สำหรับ navigator.credentials.get
+func BeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	challenge, err := authService.BeginPasskeyLogin()
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    challenge,
+	})
+}
+
+// FinishPasskeyLogin ตรวจสอบ assertion ของ passkey แล้วออก JWT
+func FinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePasskeyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := authService.LoginWithPasskey(req.SessionToken, req.Credential, clientInfo(r))
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	writeAuthResult(w, result)
+}
+
+// GetPasskeys ดึง passkeys ของ user ปัจจุบัน
+func GetPasskeys(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	credentials, err := passkeyService.ListCredentials(claims.UserID)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    credentials,
+	})
+}
+
+// BeginPasskeyRegistration สร้าง options สำหรับ navigator.credentials.create
+func BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	challenge, err := passkeyService.BeginRegistration(claims.UserID)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    challenge,
+	})
+}
+
+// FinishPasskeyRegistration ตรวจสอบ attestation แล้วบันทึก passkey ของ user ปัจจุบัน
+func FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	req, ok := decodePasskeyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	credential, err := passkeyService.FinishRegistration(claims.UserID, req.SessionToken, req.Name, req.Credential)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    credential,
+	})
+}
+
+// DeletePasskey ลบ passkey ของ user ปัจจุบัน
+func DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Invalid passkey ID format")
+		return
+	}
+
+	if err := passkeyService.DeleteCredential(claims.UserID, uint(id)); err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var personalAccessTokenService services.PersonalAccessTokenService
+
+// InitPersonalAccessTokenController กำหนด service ที่ใช้ร่วมกับ AuthMiddleware
+func InitPersonalAccessTokenController(tokens services.PersonalAccessTokenService) {
+	personalAccessTokenService = tokens
+}
+
+// writePersonalAccessTokenError แปลง error ของ personal access token เป็น HTTP status
+func writePersonalAccessTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTokenName),
+		errors.Is(err, services.ErrInvalidTokenScope),
+		errors.Is(err, services.ErrInvalidTokenExpiry):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrUserInactive):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repositories.ErrPersonalAccessTokenNotFound):
+		writeJSONError(w, http.StatusNotFound, "Token not found")
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	default:
+		log.Printf("Personal access token error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// GetPersonalAccessTokens ดึง personal access tokens ของ user ปัจจุบัน (ไม่มีตัว token)
+func GetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokens, err := personalAccessTokenService.ListTokens(claims.UserID)
+	if err != nil {
+		writePersonalAccessTokenError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// CreatePersonalAccessToken สร้าง token ใหม่ คืนตัว token ครั้งเดียว
+func CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	token, err := personalAccessTokenService.CreateToken(claims.UserID, claims.OrgID, req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		writePersonalAccessTokenError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    token,
+	})
+}
+
+// RevokePersonalAccessToken เพิกถอน token ของ user ปัจจุบัน
+func RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Invalid token ID format")
+		return
+	}
+
+	if err := personalAccessTokenService.RevokeToken(claims.UserID, uint(id)); err != nil {
+		writePersonalAccessTokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
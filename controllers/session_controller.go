@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"collp-backend/middleware"
+	"collp-backend/repositories"
+	"collp-backend/services"
+)
+
+var sessionService services.SessionService
+
+// writeSessionError แปลง error ของ session เป็น HTTP status
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrSessionNotFound):
+		writeJSONError(w, http.StatusNotFound, "Session not found")
+	default:
+		log.Printf("Session error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// parseSessionID อ่าน session ID จาก path parameter
+func parseSessionID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("Invalid session ID format")
+	}
+	return uint(id), nil
+}
+
+// GetSessions ดึงอุปกรณ์ที่ user ปัจจุบัน login อยู่
+func GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessions, err := sessionService.ListSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+// DeleteSession ปิด session ของ user ปัจจุบัน (logout อุปกรณ์นั้น)
+func DeleteSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := parseSessionID(r, "id")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := sessionService.RevokeSession(claims.UserID, claims.UserID, id); err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserSessions ดึงอุปกรณ์ที่ user ที่ระบุ login อยู่ (สำหรับ admin)
+func GetUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := sessionService.ListSessions(userID, 0)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+// DeleteUserSession ปิด session ของ user ที่ระบุ (สำหรับ admin)
+func DeleteUserSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseSessionID(r, "session_id")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := sessionService.RevokeSession(claims.UserID, userID, id); err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
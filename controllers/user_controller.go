@@ -1,10 +1,15 @@
 package controllers
 
 import (
+	"collp-backend/middleware"
+	"collp-backend/models"
 	"collp-backend/repositories"
 	"collp-backend/services"
+	"collp-backend/validators"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -12,39 +17,173 @@ import (
 )
 
 var userService services.UserService
+var auditService services.AuditService
 
 // InitUserController initialize user service
-func InitUserController(db *gorm.DB) {
+func InitUserController(db *gorm.DB, revocations services.TokenRevocationService) {
 	userRepo := repositories.NewUserRepository(db)
-	userService = services.NewUserService(userRepo)
+	roleRepo := repositories.NewRoleRepository(db)
+	auditService = services.NewAuditService(repositories.NewAuditLogRepository(db))
+	userService = services.NewUserService(userRepo, roleRepo, repositories.NewUserIdentityRepository(db), repositories.NewMembershipRepository(db), revocations, auditService)
+}
+
+// parseUserID อ่าน user ID จาก path parameter :id
+func parseUserID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("Invalid user ID format")
+	}
+	return uint(id), nil
+}
+
+// organizationUserID อ่าน user ID จาก path parameter :id และตรวจว่าเป็นสมาชิกของ organization
+// ที่ผู้เรียกใช้งานอยู่ user ของ organization อื่นตอบ 404 เหมือนไม่มี user นั้น
+func organizationUserID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := parseUserID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+
+	if _, err := userService.GetOrganizationUser(claims.OrgID, id); err != nil {
+		writeUserServiceError(w, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// removedFromOrganization admin ของ organization (role ของระบบไม่ใช่ admin) ปิดการใช้งานหรือลบบัญชี
+// ที่อาจเป็นสมาชิกของ organization อื่นด้วยไม่ได้ จึงนำ user ออกจาก organization ที่ใช้งานอยู่แทน
+// คืน true เมื่อจัดการแล้ว (ตอบ response หรือ error ไปแล้ว) ลบ user ตอบ 204 เหมือนการลบบัญชี
+func removedFromOrganization(w http.ResponseWriter, r *http.Request, id uint, status int) bool {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return true
+	}
+	if claims.Role == models.RoleAdmin {
+		return false
+	}
+
+	if err := userService.RemoveFromOrganization(claims.UserID, claims.OrgID, id); err != nil {
+		writeUserServiceError(w, err)
+		return true
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return true
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"success": true,
+		"message": "User removed from organization",
+	})
+	return true
+}
+
+// parsePagination อ่าน page และ limit จาก query string
+func parsePagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	return page, limit
+}
+
+// writeUserServiceError แปลง error จาก user service เป็น HTTP status
+func writeUserServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrUserExists):
+		writeJSONError(w, http.StatusConflict, "Email is already registered")
+	case errors.Is(err, services.ErrLastOwner):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidInput):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrNoActiveOrganization):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	default:
+		log.Printf("User service error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// CreateUser สร้าง user ใหม่
+func CreateUser(w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		Email   string `json:"email"`
+		Name    string `json:"name"`
+		Avatar  string `json:"avatar"`
+		Phone   string `json:"phone"`
+		Address string `json:"address"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// user ใหม่เป็นสมาชิกของ organization ที่ใช้งานอยู่
+	user, err := userService.CreateOrganizationUser(claims.OrgID, &models.User{
+		Email:   reqBody.Email,
+		Name:    reqBody.Name,
+		Avatar:  reqBody.Avatar,
+		Phone:   reqBody.Phone,
+		Address: reqBody.Address,
+	})
+	if err != nil {
+		writeUserServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/users/"+strconv.FormatUint(uint64(user.ID), 10))
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    user,
+	})
 }
 
 // GetUserByID ดึงข้อมูล user ตาม ID
 func GetUserByID(w http.ResponseWriter, r *http.Request) {
-	// Parse ID from URL parameter
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		writeJSONError(w, http.StatusBadRequest, "User ID is required")
+	id, err := parseUserID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "Invalid user ID format")
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Get user from service
-	user, err := userService.GetUserByID(uint(id))
+	// Get user ของ organization ที่ใช้งานอยู่จาก service
+	user, err := userService.GetOrganizationUser(claims.OrgID, id)
 	if err != nil {
-		writeJSONError(w, http.StatusNotFound, err.Error())
+		writeUserServiceError(w, err)
 		return
 	}
 
 	// Return user data
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    user,
 	})
@@ -52,24 +191,18 @@ func GetUserByID(w http.ResponseWriter, r *http.Request) {
 
 // GetAllUsers ดึงรายการ users ทั้งหมดแบบ pagination
 func GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	// Parse pagination parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page <= 0 {
-		page = 1
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
-	}
+	page, limit := parsePagination(r)
 
-	// Get users from service
-	users, total, err := userService.GetAllUsers(page, limit)
+	// Get users ของ organization ที่ใช้งานอยู่จาก service
+	users, total, err := userService.GetAllUsers(claims.OrgID, page, limit)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		writeUserServiceError(w, err)
 		return
 	}
 
@@ -77,9 +210,7 @@ func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
 	// Return paginated data
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data": map[string]interface{}{
 			"users":       users,
@@ -93,22 +224,27 @@ func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUserProfile อัพเดท profile ของ user
 func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		writeJSONError(w, http.StatusMethodNotAllowed, "Only PUT method allowed")
+	id, err := parseUserID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Parse user ID
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		writeJSONError(w, http.StatusBadRequest, "User ID is required")
+	// แก้ไขได้เฉพาะ profile ของตัวเอง เว้นแต่มีสิทธิ์ users:update
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "Invalid user ID format")
-		return
+	if claims.UserID != id {
+		if !claims.HasPermission(models.PermUsersUpdate) {
+			writeJSONError(w, http.StatusForbidden, "You can only update your own profile")
+			return
+		}
+		if _, err := userService.GetOrganizationUser(claims.OrgID, id); err != nil {
+			writeUserServiceError(w, err)
+			return
+		}
 	}
 
 	// Parse request body
@@ -123,15 +259,13 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update user profile
-	if err := userService.UpdateUserProfile(uint(id), reqBody.Name, reqBody.Avatar); err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+	if err := userService.UpdateUserProfile(id, reqBody.Name, reqBody.Avatar); err != nil {
+		writeUserServiceError(w, err)
 		return
 	}
 
 	// Return success response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "User profile updated successfully",
 	})
@@ -139,34 +273,22 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 
 // DeactivateUser ปิดการใช้งาน user
 func DeactivateUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPatch {
-		writeJSONError(w, http.StatusMethodNotAllowed, "Only PATCH method allowed")
+	id, ok := organizationUserID(w, r)
+	if !ok {
 		return
 	}
-
-	// Parse user ID
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		writeJSONError(w, http.StatusBadRequest, "User ID is required")
-		return
-	}
-
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "Invalid user ID format")
+	if removedFromOrganization(w, r, id, http.StatusOK) {
 		return
 	}
 
 	// Deactivate user
-	if err := userService.DeactivateUser(uint(id)); err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+	if err := userService.DeactivateUser(id); err != nil {
+		writeUserServiceError(w, err)
 		return
 	}
 
 	// Return success response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "User deactivated successfully",
 	})
@@ -174,76 +296,158 @@ func DeactivateUser(w http.ResponseWriter, r *http.Request) {
 
 // ActivateUser เปิดการใช้งาน user
 func ActivateUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPatch {
-		writeJSONError(w, http.StatusMethodNotAllowed, "Only PATCH method allowed")
+	id, ok := organizationUserID(w, r)
+	if !ok {
 		return
 	}
 
-	// Parse user ID
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		writeJSONError(w, http.StatusBadRequest, "User ID is required")
+	// Activate user
+	if err := userService.ActivateUser(id); err != nil {
+		writeUserServiceError(w, err)
 		return
 	}
 
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "Invalid user ID format")
+	// Return success response
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "User activated successfully",
+	})
+}
+
+// DeleteUser ลบ user (soft delete)
+func DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+	if removedFromOrganization(w, r, id, http.StatusNoContent) {
 		return
 	}
 
-	// Activate user
-	if err := userService.ActivateUser(uint(id)); err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+	// Delete user
+	if err := userService.DeleteUser(id); err != nil {
+		writeUserServiceError(w, err)
 		return
 	}
 
-	// Return success response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HardDeleteUser ลบ user ออกจากฐานข้อมูลถาวร
+func HardDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+	if removedFromOrganization(w, r, id, http.StatusNoContent) {
+		return
+	}
+
+	if err := userService.HardDeleteUser(id); err != nil {
+		writeUserServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignUserRole เปลี่ยน role ของ user
+func AssignUserRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := userService.AssignRole(id, reqBody.Role); err != nil {
+		writeUserServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "User activated successfully",
+		"message": "User role updated successfully",
 	})
 }
 
-// DeleteUser ลบ user (soft delete)
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		writeJSONError(w, http.StatusMethodNotAllowed, "Only DELETE method allowed")
+// UnlockUser ปลดล็อคบัญชีที่ถูกล็อคจากการ login ผิด
+func UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Parse user ID
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		writeJSONError(w, http.StatusBadRequest, "User ID is required")
+	if err := userService.UnlockUser(id, claims.UserID); err != nil {
+		writeUserServiceError(w, err)
 		return
 	}
 
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "User unlocked successfully",
+	})
+}
+
+// GetUserAuditLogs ดึง audit log ของ user
+func GetUserAuditLogs(w http.ResponseWriter, r *http.Request) {
+	id, ok := organizationUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := parsePagination(r)
+	entries, total, err := auditService.GetUserAuditLogs(id, page, limit)
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "Invalid user ID format")
+		writeUserServiceError(w, err)
 		return
 	}
 
-	// Delete user
-	if err := userService.DeleteUser(uint(id)); err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"audit_logs": entries,
+			"total":      total,
+			"page":       page,
+			"limit":      limit,
+		},
+	})
+}
+
+// GetRoles ดึงรายการ roles และ permissions
+func GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := userService.GetRoles()
+	if err != nil {
+		writeUserServiceError(w, err)
 		return
 	}
 
-	// Return success response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "User deleted successfully",
+		"data":    roles,
 	})
 }
 
 // SearchUsers ค้นหา users
 func SearchUsers(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
 	// Parse search parameters
 	keyword := r.URL.Query().Get("q")
 	if keyword == "" {
@@ -251,23 +455,12 @@ func SearchUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page <= 0 {
-		page = 1
-	}
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
-	}
+	page, limit := parsePagination(r)
 
 	// Search users
-	users, total, err := userService.SearchUsers(keyword, page, limit)
+	users, total, err := userService.SearchUsers(claims.OrgID, keyword, page, limit)
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+		writeUserServiceError(w, err)
 		return
 	}
 
@@ -275,9 +468,7 @@ func SearchUsers(w http.ResponseWriter, r *http.Request) {
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
 	// Return search results
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data": map[string]interface{}{
 			"users":       users,
@@ -290,58 +481,107 @@ func SearchUsers(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetUserStats ดึงสถิติของ users
+// GetUserStats ดึงสถิติของสมาชิกใน organization ที่ใช้งานอยู่
 func GetUserStats(w http.ResponseWriter, r *http.Request) {
-	// Get stats from service
-	stats, err := userService.GetUserStats()
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get stats ของ organization ที่ใช้งานอยู่จาก service
+	stats, err := userService.GetUserStats(claims.OrgID)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		writeUserServiceError(w, err)
 		return
 	}
 
 	// Return stats
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    stats,
 	})
 }
 
-// CollPLogin (Legacy - keep for backward compatibility)
+// CollPLogin เข้าสู่ระบบด้วย email และ password
 func CollPLogin(w http.ResponseWriter, r *http.Request) {
-	// For now, return a placeholder response
-	// TODO: Implement proper login logic with userService
-	response := map[string]interface{}{
-		"success": false,
-		"message": "Login endpoint is being refactored. Please use OAuth login instead.",
+	var req validators.UserLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := validators.ValidateUserLogin(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	result, err := authService.Login(req, clientInfo(r))
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrInvalidCredentials):
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, services.ErrUserInactive):
+			writeJSONError(w, http.StatusForbidden, err.Error())
+		default:
+			log.Printf("Login failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "Login failed")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
 }
 
-// CollPRegister (Legacy - keep for backward compatibility)
+// CollPRegister สมัครสมาชิกด้วย email และ password
 func CollPRegister(w http.ResponseWriter, r *http.Request) {
-	// Get headers for logging purposes
-	username := r.Header.Get("username")
-	password := r.Header.Get("password")
-	phone := r.Header.Get("phone")
-	address := r.Header.Get("address")
+	var req validators.UserRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
 
-	// Log registration attempt
-	log.Printf("Legacy registration attempt - Username: %s, Phone: %s, Address: %s", username, phone, address)
-	if password != "" {
-		log.Printf("Password provided: yes")
+	if err := validators.ValidateUserRegistration(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// For now, return a placeholder response
-	// TODO: Implement proper registration logic with userService
-	response := map[string]interface{}{
-		"success": false,
-		"message": "Registration endpoint is being refactored. Please use OAuth registration instead.",
+	result, err := authService.Register(req, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, services.ErrUserExists) {
+			writeJSONError(w, http.StatusConflict, "Email is already registered")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInvitation) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvitationEmailMismatch) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		log.Printf("Registration failed: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Registration failed")
+		return
+	}
+
+	// ส่งไม่สำเร็จไม่ทำให้สมัครล้มเหลว user ขอส่งใหม่ได้ที่ /api/auth/verify-email/resend
+	// สมัครผ่านลิงก์คำเชิญถือว่ายืนยัน email แล้ว ไม่ต้องส่ง
+	if result.User.EmailVerifiedAt == nil {
+		if err := emailVerificationService.SendVerification(result.User); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", result.User.ID, err)
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
 }
@@ -0,0 +1,63 @@
+// Package mailer sends transactional emails such as verification links.
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// validate rejects header injection through To or Subject
+func (m Message) validate() error {
+	if m.To == "" {
+		return errors.New("message has no recipient")
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return errors.New("message headers must not contain line breaks")
+	}
+	return nil
+}
+
+// NewFromEnv creates the mailer selected by MAILER (smtp|file|log, default log).
+//
+//	smtp: SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, MAIL_FROM
+//	file: MAIL_FILE_DIR (one .eml file per message)
+func NewFromEnv() (Mailer, error) {
+	switch os.Getenv("MAILER") {
+	case "", "log":
+		return NewLogMailer(), nil
+	case "file":
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			return nil, errors.New("MAIL_FILE_DIR is required for the file mailer")
+		}
+		return NewFileMailer(dir, os.Getenv("MAIL_FROM"))
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", os.Getenv("MAILER"))
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientIPContextKey key ของ client IP ใน request context (สำหรับ net/http handlers)
+type clientIPContextKey struct{}
+
+// ClientIPMiddleware เก็บ client IP ที่ gin คำนวณตาม trusted proxies ไว้ใน request context
+// engine ต้องตั้ง SetTrustedProxies (TRUSTED_PROXIES) ไม่อย่างนั้น gin เชื่อ X-Forwarded-For จากทุก client
+// และ client เปลี่ยน IP ได้ทุก request จนการล็อค IP ของ LoginThrottleService ไม่ทำงาน
+func ClientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientIPContextKey{}, c.ClientIP()))
+		c.Next()
+	}
+}
+
+// ClientIP อ่าน client IP ของ request ถ้าไม่ผ่าน ClientIPMiddleware จะใช้ RemoteAddr
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
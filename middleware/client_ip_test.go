@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "no proxy", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "spoofed header without trusted proxies", remoteAddr: "192.0.2.1:1234", forwarded: "198.51.100.7", want: "192.0.2.1"},
+		{name: "spoofed header from untrusted client", proxies: []string{"10.0.0.0/8"}, remoteAddr: "192.0.2.1:1234", forwarded: "198.51.100.7", want: "192.0.2.1"},
+		{name: "header from trusted proxy", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", forwarded: "198.51.100.7", want: "198.51.100.7"},
+		{name: "client prepends a fake hop", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", forwarded: "203.0.113.9, 198.51.100.7", want: "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if err := router.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatalf("SetTrustedProxies error: %v", err)
+			}
+			router.Use(ClientIPMiddleware())
+			// handler แบบ net/http เหมือน CollPLogin ที่ส่ง IP ให้ LoginThrottleService
+			router.GET("/ip", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(ClientIP(r)))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+	if got := ClientIP(req); got != "192.0.2.1" {
+		t.Errorf("ClientIP = %q, want %q", got, "192.0.2.1")
+	}
+}
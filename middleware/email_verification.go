@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// emailVerificationRequired EMAIL_VERIFICATION_POLICY=required ห้าม user ที่ยังไม่ยืนยัน email ใช้ private routes
+func emailVerificationRequired() bool {
+	return os.Getenv("EMAIL_VERIFICATION_POLICY") == "required"
+}
+
+// RequireVerifiedEmail ตอบ 403 ถ้า token เป็นของ user ที่ยังไม่ยืนยัน email (ต้องใช้หลัง AuthMiddleware)
+// ถ้าไม่ได้เปิด policy จะผ่านทุก request
+func RequireVerifiedEmail() gin.HandlerFunc {
+	required := emailVerificationRequired()
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		claims, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		// OAuth clients ไม่มี email ให้ยืนยัน
+		if !claims.EmailVerified && !claims.IsServiceClient() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+			return
+		}
+
+		c.Next()
+	}
+}
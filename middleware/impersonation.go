@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejectImpersonation ตอบ 403 ถ้า admin กำลัง impersonate user อยู่ (ต้องใช้หลัง AuthMiddleware)
+// ใช้กับ endpoints ที่ลบหรือเปลี่ยนข้อมูลสำคัญ และการจัดการวิธี login ของ user
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if claims.IsImpersonated() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This action is not allowed while impersonating a user"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,21 +1,79 @@
 package middleware
+
 import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/utils"
+
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"crypto/rsa"
-	"log"
 )
 
-// Store public key in package variable or inject via function
-var publicKey *rsa.PublicKey
-// Call this once from your app init with your loaded RSA public key
-func SetPublicKey(key *rsa.PublicKey) {
-	publicKey = key
+// AccessTokenCookie ชื่อ HttpOnly cookie ที่ใช้ส่ง JWT แทน Authorization header
+const AccessTokenCookie = "access_token"
+
+// Store signing keys in package variable or inject via function
+var signingKeys *utils.KeyManager
+
+// Call this once from your app init with your loaded key manager
+func SetKeyManager(keys *utils.KeyManager) {
+	signingKeys = keys
 }
+
+// claimsKey key ของ *utils.JWTClaims ใน gin.Context
+const claimsKey = "claims"
+
+// claimsContextKey key ของ *utils.JWTClaims ใน request context (สำหรับ net/http handlers)
+type claimsContextKey struct{}
+
+// CurrentUser อ่าน claims ของ user ที่ผ่าน AuthMiddleware แล้วจาก gin.Context
+func CurrentUser(c *gin.Context) (*utils.JWTClaims, bool) {
+	value, exists := c.Get(claimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*utils.JWTClaims)
+	return claims, ok
+}
+
+// ClaimsFromContext อ่าน claims ของ user ที่ผ่าน AuthMiddleware แล้วจาก request context
+// ใช้ใน net/http handlers ที่ครอบด้วย gin.WrapF
+func ClaimsFromContext(ctx context.Context) (*utils.JWTClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*utils.JWTClaims)
+	return claims, ok
+}
+
+// TokenRevocationChecker ตรวจสอบว่า token ถูกเพิกถอนแล้วหรือไม่ (logout, ปิดการใช้งาน user)
+type TokenRevocationChecker interface {
+	IsTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+var revocationChecker TokenRevocationChecker
+
+// SetRevocationChecker กำหนด store ที่ AuthMiddleware ใช้ตรวจสอบ token ที่ถูกเพิกถอน
+func SetRevocationChecker(checker TokenRevocationChecker) {
+	revocationChecker = checker
+}
+
+// SessionActivityRecorder บันทึกว่า session ของ token ยังถูกใช้งานอยู่ (last_seen_at)
+type SessionActivityRecorder interface {
+	RecordActivity(sessionID uint, ip, userAgent string)
+}
+
+var sessionActivity SessionActivityRecorder
+
+// SetSessionActivityRecorder กำหนด recorder ที่ AuthMiddleware ใช้อัพเดท session ของ token ที่ผ่านการตรวจสอบ
+func SetSessionActivityRecorder(recorder SessionActivityRecorder) {
+	sessionActivity = recorder
+}
+
 func extractTokenFromHeader(authHeader string) (string, error) {
 	parts := strings.SplitN(authHeader, " ", 2)
 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
@@ -23,38 +81,91 @@ func extractTokenFromHeader(authHeader string) (string, error) {
 	}
 	return parts[1], nil
 }
-func AuthMiddleware(next http.Handler) http.Handler {
-	if publicKey == nil {
-		log.Fatal("public key is not set in AuthMiddleware")
+
+// ExtractToken อ่าน token จาก Authorization header หรือจาก cookie ถ้าไม่มี header
+func ExtractToken(r *http.Request) (string, error) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		return extractTokenFromHeader(authHeader)
 	}
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-		tokenString, err := extractTokenFromHeader(authHeader)
+	if cookie, err := r.Cookie(AccessTokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	return "", errors.New("Missing Authorization header")
+}
+
+// AuthMiddleware ตรวจสอบ JWT แล้วเก็บ claims ไว้ใน gin.Context และ request context
+// ถ้า token ไม่ถูกต้องจะ abort chain ทันทีด้วย 401
+func AuthMiddleware() gin.HandlerFunc {
+	if signingKeys == nil {
+		log.Fatal("key manager is not set in AuthMiddleware")
+	}
+	return func(c *gin.Context) {
+		claims, status, err := authenticate(c.Request)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
 			return
 		}
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg is RS256
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return publicKey, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
-			return
+
+		// token ระหว่าง impersonate ไม่นับเป็นการใช้งานของ user เจ้าของ session
+		if sessionActivity != nil && claims.SessionID != 0 && !claims.IsImpersonated() {
+			sessionActivity.RecordActivity(claims.SessionID, ClientIP(c.Request), c.Request.UserAgent())
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-			return
+
+		c.Set(claimsKey, claims)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), claimsContextKey{}, claims))
+		c.Next()
+	}
+}
+
+// authenticate อ่านและตรวจสอบ token ของ request คืน HTTP status ที่ควรตอบเมื่อไม่ผ่าน
+func authenticate(r *http.Request) (*utils.JWTClaims, int, error) {
+	tokenString, err := ExtractToken(r)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	if strings.HasPrefix(tokenString, models.PersonalAccessTokenPrefix) {
+		return authenticatePersonalAccessToken(r, tokenString)
+	}
+
+	// KeyManager.Keyfunc ตรวจสอบ alg และเลือก public key ตาม kid
+	token, err := jwt.ParseWithClaims(tokenString, &utils.JWTClaims{}, signingKeys.Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, http.StatusUnauthorized, fmt.Errorf("Invalid token: %v", err)
+	}
+	claims, ok := token.Claims.(*utils.JWTClaims)
+	if !ok || claims.IssuedAt == nil {
+		return nil, http.StatusUnauthorized, errors.New("Invalid token claims")
+	}
+
+	if revocationChecker != nil {
+		revoked, err := revocationChecker.IsTokenRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time)
+		if err != nil {
+			log.Printf("Failed to check token revocation: %v", err)
+			return nil, http.StatusInternalServerError, errors.New("Failed to verify token")
 		}
-		ctx := context.WithValue(r.Context(), "user", claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+		if revoked {
+			return nil, http.StatusUnauthorized, errors.New("Token has been revoked")
+		}
+	}
+
+	return claims, http.StatusOK, nil
+}
+
+// authenticatePersonalAccessToken ตรวจสอบ personal access token (ไม่ผ่าน JWT revocation เพราะเพิกถอนรายตัวได้)
+func authenticatePersonalAccessToken(r *http.Request, token string) (*utils.JWTClaims, int, error) {
+	if personalAccessTokens == nil {
+		return nil, http.StatusUnauthorized, errors.New("Personal access tokens are not enabled")
+	}
+
+	claims, err := personalAccessTokens.AuthenticatePersonalAccessToken(token, ClientIP(r))
+	if err != nil {
+		log.Printf("Failed to verify personal access token: %v", err)
+		return nil, http.StatusInternalServerError, errors.New("Failed to verify token")
+	}
+	if claims == nil {
+		return nil, http.StatusUnauthorized, errors.New("Invalid, expired or revoked personal access token")
+	}
+
+	return claims, http.StatusOK, nil
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"collp-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PersonalAccessTokenAuthenticator ตรวจสอบ personal access token แทน JWT
+// คืน nil claims (และ nil error) เมื่อ token ไม่ถูกต้อง หมดอายุ ถูกเพิกถอน หรือ user ถูกปิดการใช้งาน
+type PersonalAccessTokenAuthenticator interface {
+	AuthenticatePersonalAccessToken(token, ip string) (*utils.JWTClaims, error)
+}
+
+var personalAccessTokens PersonalAccessTokenAuthenticator
+
+// SetPersonalAccessTokenAuthenticator เปิดให้ AuthMiddleware รับ personal access tokens
+func SetPersonalAccessTokenAuthenticator(authenticator PersonalAccessTokenAuthenticator) {
+	personalAccessTokens = authenticator
+}
+
+// RequireSessionToken ตอบ 403 ถ้า request ใช้ personal access token หรือ token ของ OAuth client
+// (ต้องใช้หลัง AuthMiddleware) ใช้กับ endpoints ที่จัดการวิธี login และ tokens
+// เพื่อไม่ให้ token ที่หลุดไปสร้าง credentials เพิ่มได้
+func RequireSessionToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if claims.IsPersonalAccessToken() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot be used for this endpoint"})
+			return
+		}
+		if claims.IsServiceClient() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "OAuth client tokens cannot be used for this endpoint"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Audit actions
+const (
+	AuditAccountLocked        = "account.locked"
+	AuditAccountUnlocked      = "account.unlocked"
+	AuditAccountReclaimed     = "account.reclaimed"
+	AuditLoginIPLocked        = "login.ip_locked"
+	AuditPasswordReset        = "password.reset"
+	AuditMFAEnabled           = "mfa.enabled"
+	AuditMFADisabled          = "mfa.disabled"
+	AuditMFARecoveryUsed      = "mfa.recovery_code_used"
+	AuditMFAPolicy            = "role.mfa_policy_changed"
+	AuditPasskeyAdded         = "passkey.added"
+	AuditPasskeyRemoved       = "passkey.removed"
+	AuditPasskeyCloned        = "passkey.clone_detected"
+	AuditIdentityLinked       = "identity.linked"
+	AuditIdentityRemoved      = "identity.unlinked"
+	AuditPATCreated           = "pat.created"
+	AuditPATRevoked           = "pat.revoked"
+	AuditClientCreated        = "oauth_client.created"
+	AuditClientRotated        = "oauth_client.secret_rotated"
+	AuditClientDeleted        = "oauth_client.deleted"
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonationStopped = "impersonation.stopped"
+	AuditSessionRevoked       = "session.revoked"
+	AuditOrganizationCreated  = "organization.created"
+	AuditMemberAdded          = "organization.member_added"
+	AuditMemberRoleChanged    = "organization.member_role_changed"
+	AuditMemberRemoved        = "organization.member_removed"
+	AuditInvitationSent       = "invitation.sent"
+	AuditInvitationAccepted   = "invitation.accepted"
+	AuditInvitationRevoked    = "invitation.revoked"
+)
+
+// AuditLog บันทึกเหตุการณ์ด้าน security ที่ต้องตรวจสอบย้อนหลังได้
+type AuditLog struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// Action ประเภทเหตุการณ์ เช่น account.locked
+	Action string `json:"action" gorm:"not null;index"`
+	// ActorID user ที่ทำ action (nil = ระบบ)
+	ActorID *uint `json:"actor_id,omitempty" gorm:"index"`
+	// UserID user ที่ได้รับผลกระทบ
+	UserID    *uint     `json:"user_id,omitempty" gorm:"index"`
+	IP        string    `json:"ip,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
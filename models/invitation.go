@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationExpired  = "expired"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation คำเชิญ email เข้า organization ด้วย role ที่กำหนด (token ในลิงก์เก็บเฉพาะ hash)
+type Invitation struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	OrganizationID uint   `json:"organization_id" gorm:"not null;index"`
+	Email          string `json:"email" gorm:"not null;index"`
+	Role           string `json:"role" gorm:"not null"`
+	Status         string `json:"status" gorm:"not null;default:pending;index"`
+	TokenHash      string `json:"-" gorm:"uniqueIndex;not null"`
+	// InvitedByID user ที่เชิญ (หรือส่งคำเชิญซ้ำล่าสุด)
+	InvitedByID    uint          `json:"invited_by_id" gorm:"not null"`
+	AcceptedUserID *uint         `json:"accepted_user_id,omitempty"`
+	ExpiresAt      time.Time     `json:"expires_at" gorm:"not null"`
+	LastSentAt     time.Time     `json:"last_sent_at"`
+	AcceptedAt     *time.Time    `json:"accepted_at,omitempty"`
+	RevokedAt      *time.Time    `json:"revoked_at,omitempty"`
+	Organization   *Organization `json:"organization,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
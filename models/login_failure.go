@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginIPFailure จำนวนครั้งที่ login ผิดจาก IP เดียวกันภายในช่วงเวลาหนึ่ง
+type LoginIPFailure struct {
+	IP             string     `json:"ip" gorm:"primaryKey"`
+	FailedAttempts int        `json:"failed_attempts" gorm:"not null;default:0"`
+	LastFailedAt   time.Time  `json:"last_failed_at" gorm:"not null"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OAuthClient service ที่เรียก API ด้วย client_credentials grant (เก็บเฉพาะ hash ของ secret)
+type OAuthClient struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	ClientID string `json:"client_id" gorm:"uniqueIndex;not null"`
+	Name     string `json:"name" gorm:"not null"`
+	// SecretHash SHA-256 ของ client_secret
+	SecretHash string `json:"-" gorm:"not null"`
+	// Scopes permissions ที่ client ขอได้ (คั่นด้วย ,)
+	Scopes    string `json:"scopes"`
+	CreatedBy *uint  `json:"created_by,omitempty"`
+	// OrganizationID organization ที่ผู้สร้างใช้งานอยู่ตอนสร้าง client (ใช้ได้เท่าที่ผู้สร้างยังเป็นสมาชิก)
+	OrganizationID *uint      `json:"organization_id,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName ใช้ oauth_clients แทน o_auth_clients ที่ GORM ตั้งให้
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Organization roles (role ของ user ภายใน organization แยกจาก role ของระบบใน User.Role)
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// ValidOrgRole ตรวจสอบว่าเป็น role ของ organization ที่รู้จัก
+func ValidOrgRole(role string) bool {
+	switch role {
+	case OrgRoleOwner, OrgRoleAdmin, OrgRoleMember:
+		return true
+	}
+	return false
+}
+
+// CanManageMembers owner และ admin ของ organization เพิ่ม/ลบสมาชิกและเปลี่ยน role ได้
+func CanManageMembers(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin
+}
+
+// Organization บริษัท (tenant) ที่ใช้ CollP
+type Organization struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership user เป็นสมาชิกของ organization ด้วย role ของ organization นั้น
+type Membership struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	OrganizationID uint          `json:"organization_id" gorm:"not null;uniqueIndex:idx_memberships_org_user"`
+	UserID         uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_memberships_org_user;index"`
+	Role           string        `json:"role" gorm:"not null;default:member"`
+	Organization   *Organization `json:"organization,omitempty"`
+	User           *User         `json:"user,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// PersonalAccessTokenPrefix ขึ้นต้นทุก personal access token เพื่อแยกจาก JWT
+const PersonalAccessTokenPrefix = "collp_pat_"
+
+// PersonalAccessToken token ระยะยาวสำหรับ scripts และ CI (เก็บเฉพาะ hash และ prefix)
+type PersonalAccessToken struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	Name   string `json:"name" gorm:"not null"`
+	// Prefix ส่วนต้นของ token ที่แสดงให้ user จำได้ว่าเป็น token ไหน
+	Prefix    string `json:"prefix" gorm:"not null"`
+	TokenHash string `json:"-" gorm:"uniqueIndex;not null"`
+	// Scopes permissions ที่ token ใช้ได้ (คั่นด้วย ,) ไม่เกิน permissions ของ role ปัจจุบัน
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// OrganizationID organization ที่ใช้งานอยู่ตอนสร้าง token (ใช้ได้เท่าที่ user ยังเป็นสมาชิก)
+	OrganizationID *uint `json:"organization_id,omitempty"`
+}
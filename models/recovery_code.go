@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode รหัสสำรองแบบใช้ครั้งเดียวสำหรับ login เมื่อไม่มี authenticator (เก็บเฉพาะ hash)
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RefreshToken เก็บ refresh token แบบ hash โดยทุก token ที่หมุนต่อกันจะอยู่ใน family เดียวกัน
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	FamilyID  string     `json:"family_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// AccessTokenID jti ของ access token ที่ออกคู่กับ refresh token นี้ (ใช้เพิกถอนเมื่อปิด session)
+	AccessTokenID string `json:"-" gorm:"index"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RevokedToken access token (ตาม jti) ที่ถูกเพิกถอนก่อนหมดอายุ
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation access token ทุกตัวของ user ที่ออกก่อน RevokedBefore ถือว่าใช้ไม่ได้
+type UserTokenRevocation struct {
+	UserID        uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time `json:"revoked_before" gorm:"not null"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Role names
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// Permission names
+const (
+	PermUsersRead        = "users:read"
+	PermUsersCreate      = "users:create"
+	PermUsersUpdate      = "users:update"
+	PermUsersDeactivate  = "users:deactivate"
+	PermUsersDelete      = "users:delete"
+	PermUsersPurge       = "users:purge"
+	PermUsersStats       = "users:stats"
+	PermUsersUnlock      = "users:unlock"
+	PermRolesAssign      = "roles:assign"
+	PermAuditRead        = "audit:read"
+	PermClientsManage    = "clients:manage"
+	PermUsersImpersonate = "users:impersonate"
+	PermOrgsCreate       = "organizations:create"
+)
+
+// DefaultRolePermissions role และ permission เริ่มต้นที่ seed ลงฐานข้อมูลตอน migrate
+var DefaultRolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermUsersRead,
+		PermUsersCreate,
+		PermUsersUpdate,
+		PermUsersDeactivate,
+		PermUsersDelete,
+		PermUsersPurge,
+		PermUsersStats,
+		PermUsersUnlock,
+		PermRolesAssign,
+		PermAuditRead,
+		PermClientsManage,
+		PermUsersImpersonate,
+		PermOrgsCreate,
+	},
+	RoleMember: {
+		PermUsersRead,
+	},
+	RoleViewer: {},
+}
+
+// DefaultMFARoles roles ที่บังคับ 2FA เมื่อถูกสร้างครั้งแรก (เปลี่ยนภายหลังได้ผ่าน API)
+var DefaultMFARoles = map[string]bool{
+	RoleAdmin: true,
+}
+
+// Role กลุ่มของ permissions ที่กำหนดให้ user
+type Role struct {
+	Name        string `json:"name" gorm:"primaryKey"`
+	Description string `json:"description"`
+	// RequireMFA user ใน role นี้ต้องเปิด 2FA ก่อนจึงจะ login ได้
+	RequireMFA  bool         `json:"require_mfa" gorm:"not null;default:false"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;joinForeignKey:RoleName;joinReferences:PermissionName"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Permission สิทธิ์ในการทำ operation หนึ่ง เช่น users:delete
+type Permission struct {
+	Name        string    `json:"name" gorm:"primaryKey"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Session อุปกรณ์ที่ user login อยู่ หนึ่ง session ต่อหนึ่ง refresh token family
+type Session struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	FamilyID string `json:"-" gorm:"uniqueIndex;not null"`
+	// UserAgent, IP และ LastSeenAt ของ request ล่าสุด (อัพเดทไม่เกินนาทีละครั้ง)
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	// OrganizationID organization ที่ใช้งานอยู่ของ session (ใส่ใน claim org_id ทุกครั้งที่ออก token)
+	OrganizationID *uint `json:"organization_id,omitempty"`
+	// Current เป็น session ของ token ที่ใช้เรียก API อยู่ (ไม่ได้เก็บในฐานข้อมูล)
+	Current bool `json:"current" gorm:"-"`
+}
@@ -8,13 +8,35 @@ import (
 
 // User model
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Name      string         `json:"name" gorm:"not null"`
-	GoogleID  string         `json:"google_id" gorm:"uniqueIndex"`
-	Avatar    string         `json:"avatar"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Email           string     `json:"email" gorm:"uniqueIndex;not null"`
+	Name            string     `json:"name" gorm:"not null"`
+	Avatar          string     `json:"avatar"`
+	PasswordHash    string     `json:"-"`
+	Phone           string     `json:"phone,omitempty"`
+	Address         string     `json:"address,omitempty"`
+	Role            string     `json:"role" gorm:"default:member;not null;index"`
+	IsActive        bool       `json:"is_active" gorm:"default:true"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// Login lockout state (นับเฉพาะ password login ที่ผิดติดกัน)
+	FailedLoginAttempts int        `json:"failed_login_attempts" gorm:"not null;default:0"`
+	LastFailedLoginAt   *time.Time `json:"last_failed_login_at,omitempty"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	// TOTP two-factor authentication (TOTPSecret ที่ยังไม่มี TOTPEnabledAt คือกำลังลงทะเบียน)
+	TOTPSecret       string         `json:"-"`
+	TOTPEnabledAt    *time.Time     `json:"totp_enabled_at,omitempty"`
+	TOTPLastUsedStep int64          `json:"-" gorm:"not null;default:0"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// MFAEnabled ตรวจสอบว่า user เปิดใช้ 2FA แล้วหรือไม่
+func (u *User) MFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// IsLocked ตรวจสอบว่าบัญชียังถูกล็อคจากการ login ผิดหรือไม่
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
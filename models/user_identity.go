@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserIdentity บัญชีของ OIDC provider ที่ผูกกับ user (1 user ผูกได้หลาย provider)
+type UserIdentity struct {
+	ID     uint `json:"id" gorm:"primaryKey"`
+	UserID uint `json:"user_id" gorm:"not null;index"`
+	// Provider ชื่อ provider ตาม OIDC_PROVIDERS_FILE เช่น google
+	Provider string `json:"provider" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	// Subject claim sub ของ id_token (ไม่เปลี่ยนแม้ user เปลี่ยน email)
+	Subject       string     `json:"subject" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
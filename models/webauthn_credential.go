@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// WebAuthnCredential passkey ที่ user ลงทะเบียนไว้ (เก็บเฉพาะ public key)
+type WebAuthnCredential struct {
+	ID     uint `json:"id" gorm:"primaryKey"`
+	UserID uint `json:"user_id" gorm:"not null;index"`
+	// Name ชื่อที่ user ตั้งเพื่อแยกอุปกรณ์ เช่น "MacBook"
+	Name            string `json:"name" gorm:"not null"`
+	CredentialID    []byte `json:"-" gorm:"not null;uniqueIndex"`
+	PublicKey       []byte `json:"-" gorm:"not null"`
+	AttestationType string `json:"-"`
+	AAGUID          []byte `json:"-"`
+	// Transports เช่น internal,hybrid (คั่นด้วย ,)
+	Transports     string `json:"transports,omitempty"`
+	SignCount      uint32 `json:"sign_count" gorm:"not null;default:0"`
+	BackupEligible bool   `json:"backup_eligible"`
+	BackupState    bool   `json:"backup_state"`
+	// CloneWarning sign counter ถอยหลัง อาจมี authenticator ถูก clone ใช้ login ไม่ได้อีก
+	CloneWarning bool       `json:"clone_warning" gorm:"not null;default:false"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TableName ใช้ webauthn_credentials แทน web_authn_credentials ที่ GORM ตั้งให้
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
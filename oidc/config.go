@@ -0,0 +1,91 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ProviderConfig is one OpenID Connect provider in the providers file.
+// Issuer, ClientID, ClientSecret and RedirectURL may reference environment
+// variables as ${VAR} so secrets stay out of the file.
+type ProviderConfig struct {
+	// Name is the {provider} path segment, e.g. "google" or "keycloak"
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// Issuer is the URL whose /.well-known/openid-configuration is discovered
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// Config is the providers file
+type Config struct {
+	Providers []ProviderConfig `json:"providers"`
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// defaultScopes are requested when a provider does not list its own
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// LoadConfig reads a providers file and expands ${VAR} references
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OIDC providers file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC providers file: %w", err)
+	}
+	for i := range cfg.Providers {
+		p := &cfg.Providers[i]
+		p.Issuer = os.ExpandEnv(p.Issuer)
+		p.ClientID = os.ExpandEnv(p.ClientID)
+		p.ClientSecret = os.ExpandEnv(p.ClientSecret)
+		p.RedirectURL = os.ExpandEnv(p.RedirectURL)
+	}
+	return &cfg, nil
+}
+
+// validate checks the fields required to run the authorization code flow
+func (p ProviderConfig) validate() error {
+	if !providerNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid provider name %q", p.Name)
+	}
+	if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+		return fmt.Errorf("provider %q: issuer, client_id and redirect_url are required", p.Name)
+	}
+	return nil
+}
+
+// NewRegistryFromEnv loads the providers listed in OIDC_PROVIDERS_FILE.
+// Without a file, Google is configured from GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET
+// and GOOGLE_REDIRECT_URL so existing deployments keep working.
+func NewRegistryFromEnv() (*Registry, error) {
+	if path := os.Getenv("OIDC_PROVIDERS_FILE"); path != "" {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewRegistry(cfg)
+	}
+
+	cfg := &Config{}
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		cfg.Providers = append(cfg.Providers, ProviderConfig{
+			Name:         "google",
+			DisplayName:  "Google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+		})
+	}
+	return NewRegistry(cfg)
+}
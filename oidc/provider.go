@@ -0,0 +1,189 @@
+// Package oidc signs users in through OpenID Connect providers discovered from
+// their issuer's /.well-known/openid-configuration.
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrInvalidIDToken the id_token failed signature, issuer, audience, expiry or nonce checks
+	ErrInvalidIDToken = errors.New("invalid id_token")
+	// ErrCodeExchange the token endpoint rejected the authorization code
+	ErrCodeExchange = errors.New("authorization code exchange failed")
+)
+
+// httpTimeout bounds discovery, token, JWKS and userinfo requests
+const httpTimeout = 10 * time.Second
+
+// Identity is the verified end-user returned by a provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider runs the authorization code flow (with PKCE and nonce) against one issuer
+type Provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	// discovery is done lazily so an unreachable provider does not stop the server
+	mu       sync.Mutex
+	provider *gooidc.Provider
+	verifier *gooidc.IDTokenVerifier
+	oauth2   *oauth2.Config
+}
+
+// NewProvider creates a provider; discovery happens on first use
+func NewProvider(config ProviderConfig) (*Provider, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: httpTimeout},
+	}, nil
+}
+
+// Name returns the provider's path segment
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// DisplayName returns the provider's human readable name
+func (p *Provider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// context attaches the provider's HTTP client to ctx
+func (p *Provider) context(ctx context.Context) context.Context {
+	return gooidc.ClientContext(ctx, p.client)
+}
+
+// discover fetches the issuer's metadata once and caches it
+func (p *Provider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return nil
+	}
+
+	provider, err := gooidc.NewProvider(p.context(ctx), p.config.Issuer)
+	if err != nil {
+		return fmt.Errorf("OIDC discovery for %s failed: %w", p.config.Name, err)
+	}
+
+	p.provider = provider
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.config.ClientID})
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+		Endpoint:     provider.Endpoint(),
+	}
+	return nil
+}
+
+// AuthCodeURL returns the provider's authorization URL for state, nonce and the PKCE verifier
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+	return p.oauth2.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// idTokenClaims are the standard claims read from the id_token and userinfo
+type idTokenClaims struct {
+	Email         string       `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	Name          string       `json:"name"`
+	Picture       string       `json:"picture"`
+}
+
+// flexibleBool accepts true and "true"; some providers send email_verified as a string
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `true`, `"true"`:
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// Exchange redeems the authorization code and verifies the id_token
+// (signature, iss, aud, exp and nonce). Claims missing from the id_token are
+// read from the userinfo endpoint.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+	ctx = p.context(ctx)
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCodeExchange, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if nonce == "" || idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	var claims idTokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id_token claims: %w", err)
+	}
+
+	if claims.Email == "" && p.provider.UserInfoEndpoint() != "" {
+		userInfo, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch user info: %w", err)
+		}
+		// userinfo must describe the same subject as the id_token (OIDC Core 5.3.2)
+		if userInfo.Subject != idToken.Subject {
+			return nil, fmt.Errorf("%w: userinfo subject mismatch", ErrInvalidIDToken)
+		}
+		if err := userInfo.Claims(&claims); err != nil {
+			return nil, fmt.Errorf("failed to parse user info: %w", err)
+		}
+	}
+
+	return &Identity{
+		Provider:      p.config.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}, nil
+}
@@ -0,0 +1,248 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "client-123"
+	testKeyID    = "test-key"
+)
+
+// fakeProvider is an OpenID Connect provider serving discovery, JWKS, token and userinfo endpoints
+type fakeProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// issuer is announced by discovery; the server URL unless a test changes it
+	issuer string
+
+	// idToken returns the claims of the id_token issued by the token endpoint
+	idToken jwt.MapClaims
+	// userInfo is returned by the userinfo endpoint
+	userInfo map[string]any
+	// code and verifier are the values the token endpoint expects
+	code     string
+	verifier string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	f := &fakeProvider{key: key, code: "auth-code", verifier: "pkce-verifier"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                f.issuer,
+			"authorization_endpoint":                f.server.URL + "/authorize",
+			"token_endpoint":                        f.server.URL + "/token",
+			"jwks_uri":                              f.server.URL + "/jwks",
+			"userinfo_endpoint":                     f.server.URL + "/userinfo",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": testKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != f.code || r.PostFormValue("code_verifier") != f.verifier {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, f.idToken)
+		token.Header["kid"] = testKeyID
+		signed, err := token.SignedString(f.key)
+		if err != nil {
+			t.Errorf("failed to sign id_token: %v", err)
+		}
+		writeJSON(w, map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     signed,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, f.userInfo)
+	})
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	f.issuer = f.server.URL
+
+	now := time.Now()
+	f.idToken = jwt.MapClaims{
+		"iss":            f.server.URL,
+		"aud":            testClientID,
+		"sub":            "subject-1",
+		"nonce":          "nonce-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeProvider) provider(t *testing.T) *Provider {
+	t.Helper()
+	provider, err := NewProvider(ProviderConfig{
+		Name:         "fake",
+		Issuer:       f.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+	})
+	if err != nil {
+		t.Fatalf("NewProvider error: %v", err)
+	}
+	return provider
+}
+
+func TestProviderExchange(t *testing.T) {
+	tests := []struct {
+		name string
+		// modify changes the fake provider's responses before the exchange
+		modify  func(f *fakeProvider)
+		nonce   string
+		want    *Identity
+		wantErr error
+	}{
+		{
+			name:  "valid id_token",
+			nonce: "nonce-1",
+			want:  &Identity{Provider: "fake", Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name:    "wrong issuer",
+			modify:  func(f *fakeProvider) { f.idToken["iss"] = "https://evil.example.com" },
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name:    "wrong audience",
+			modify:  func(f *fakeProvider) { f.idToken["aud"] = "other-client" },
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name:    "wrong nonce",
+			nonce:   "nonce-2",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name:    "missing nonce",
+			modify:  func(f *fakeProvider) { delete(f.idToken, "nonce") },
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name:    "expired",
+			modify:  func(f *fakeProvider) { f.idToken["exp"] = time.Now().Add(-time.Hour).Unix() },
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "signed by another key",
+			modify: func(f *fakeProvider) {
+				other, _ := rsa.GenerateKey(rand.Reader, 2048)
+				f.key = other
+			},
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "claims from userinfo",
+			modify: func(f *fakeProvider) {
+				delete(f.idToken, "email")
+				delete(f.idToken, "email_verified")
+				f.userInfo = map[string]any{"sub": "subject-1", "email": "info@example.com", "email_verified": "true", "name": "Info User"}
+			},
+			nonce: "nonce-1",
+			want:  &Identity{Provider: "fake", Subject: "subject-1", Email: "info@example.com", EmailVerified: true, Name: "Info User"},
+		},
+		{
+			name: "userinfo subject mismatch",
+			modify: func(f *fakeProvider) {
+				delete(f.idToken, "email")
+				f.userInfo = map[string]any{"sub": "subject-2", "email": "victim@example.com", "email_verified": true}
+			},
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProvider(t)
+			if tt.modify != nil {
+				tt.modify(f)
+			}
+
+			identity, err := f.provider(t).Exchange(context.Background(), f.code, f.verifier, tt.nonce)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exchange error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange error: %v", err)
+			}
+			if *identity != *tt.want {
+				t.Errorf("identity = %+v, want %+v", *identity, *tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderExchangeRejectsWrongVerifier(t *testing.T) {
+	f := newFakeProvider(t)
+
+	_, err := f.provider(t).Exchange(context.Background(), f.code, "other-verifier", "nonce-1")
+	if !errors.Is(err, ErrCodeExchange) {
+		t.Fatalf("Exchange error = %v, want %v", err, ErrCodeExchange)
+	}
+}
+
+func TestProviderDiscoveryIssuerMismatch(t *testing.T) {
+	f := newFakeProvider(t)
+	f.issuer = "https://evil.example.com"
+
+	if _, err := f.provider(t).AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err == nil {
+		t.Fatal("AuthCodeURL succeeded with discovery announcing another issuer")
+	}
+}
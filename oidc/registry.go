@@ -0,0 +1,51 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownProvider no provider is configured under the requested name
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+	// order keeps the providers file order for listing
+	order []string
+}
+
+// NewRegistry validates the config and creates one provider per entry
+func NewRegistry(cfg *Config) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider)}
+	for _, providerConfig := range cfg.Providers {
+		if _, exists := registry.providers[providerConfig.Name]; exists {
+			return nil, fmt.Errorf("duplicate provider %q", providerConfig.Name)
+		}
+		provider, err := NewProvider(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		registry.providers[providerConfig.Name] = provider
+		registry.order = append(registry.order, providerConfig.Name)
+	}
+	return registry, nil
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// Providers returns all providers in configuration order
+func (r *Registry) Providers() []*Provider {
+	providers := make([]*Provider, 0, len(r.order))
+	for _, name := range r.order {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
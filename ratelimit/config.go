@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Config limits applied by the router
+type Config struct {
+	// Global per-IP limit for every request
+	Global Limit
+	// Auth per-IP limit for login, register and token endpoints
+	Auth Limit
+	// User per-user limit for authenticated routes
+	User Limit
+	// Routes limits for single routes or route groups by name (see Limiter.Route)
+	Routes map[string]Limit
+}
+
+var routeNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// DefaultConfig returns the limits used when no environment overrides are set
+func DefaultConfig() Config {
+	return Config{
+		Global: PerMinute(100),
+		Auth:   PerMinute(10),
+		User:   PerMinute(300),
+		Routes: map[string]Limit{},
+	}
+}
+
+// LoadConfig reads RATE_LIMIT_GLOBAL, RATE_LIMIT_AUTH and RATE_LIMIT_USER (e.g. "100/m")
+// and the per-route limits in RATE_LIMIT_ROUTES (e.g. "login=5/m,register=3/h")
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+	overrides := map[string]*Limit{
+		"RATE_LIMIT_GLOBAL": &cfg.Global,
+		"RATE_LIMIT_AUTH":   &cfg.Auth,
+		"RATE_LIMIT_USER":   &cfg.User,
+	}
+	for env, limit := range overrides {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		parsed, err := ParseLimit(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", env, err)
+		}
+		*limit = parsed
+	}
+
+	routes, err := ParseRouteLimits(os.Getenv("RATE_LIMIT_ROUTES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err)
+	}
+	cfg.Routes = routes
+	return cfg, nil
+}
+
+// ParseRouteLimits parses comma separated name=limit pairs such as "login=5/m,register=3/h"
+func ParseRouteLimits(value string) (map[string]Limit, error) {
+	routes := map[string]Limit{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, limitValue, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !routeNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid route limit %q, expected <name>=<count>/<s|m|h>", pair)
+		}
+		if _, exists := routes[name]; exists {
+			return nil, fmt.Errorf("duplicate route limit %q", name)
+		}
+
+		limit, err := ParseLimit(limitValue)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", name, err)
+		}
+		routes[name] = limit
+	}
+	return routes, nil
+}
+
+// NewStoreFromEnv creates the store selected by RATE_LIMIT_STORE (memory|redis).
+// The redis store connects to REDIS_URL.
+func NewStoreFromEnv() (Store, error) {
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		return NewMemoryStore(time.Minute), nil
+	case "redis":
+		client, err := newRedisClient(os.Getenv("REDIS_URL"))
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisStore(client, "ratelimit:"), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+}
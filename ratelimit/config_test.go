@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRouteLimits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]Limit
+		wantErr bool
+	}{
+		{value: "", want: map[string]Limit{}},
+		{
+			value: "login=5/m, register=3/h,",
+			want: map[string]Limit{
+				"login":    {Rate: 5, Period: time.Minute, Burst: 5},
+				"register": {Rate: 3, Period: time.Hour, Burst: 3},
+			},
+		},
+		{value: "login", wantErr: true},
+		{value: "Login=5/m", wantErr: true},
+		{value: "login=5", wantErr: true},
+		{value: "login=5/m,login=6/m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRouteLimits(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRouteLimits(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRouteLimits(%q) error: %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRouteLimits(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("RATE_LIMIT_AUTH", "20/m")
+	t.Setenv("RATE_LIMIT_ROUTES", "login=5/m")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	if cfg.Auth != PerMinute(20) {
+		t.Errorf("auth = %+v, want %+v", cfg.Auth, PerMinute(20))
+	}
+	if cfg.Global != DefaultConfig().Global {
+		t.Errorf("global = %+v, want default %+v", cfg.Global, DefaultConfig().Global)
+	}
+	if cfg.Routes["login"] != PerMinute(5) {
+		t.Errorf("login route = %+v, want %+v", cfg.Routes["login"], PerMinute(5))
+	}
+
+	t.Setenv("RATE_LIMIT_ROUTES", "login=fast")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig accepted an invalid RATE_LIMIT_ROUTES")
+	}
+}
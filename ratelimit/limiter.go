@@ -0,0 +1,99 @@
+// Package ratelimit implements GCRA (generic cell rate algorithm) rate limiting
+// with pluggable storage and a Gin middleware that sets RateLimit-* headers.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Rate requests per Period with bursts of up to Burst requests
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// PerMinute returns a limit of n requests per minute with a burst of n
+func PerMinute(n int) Limit {
+	return Limit{Rate: n, Period: time.Minute, Burst: n}
+}
+
+// ParseLimit parses limits like "100/m", "10/s" or "1000/h" (burst equals the rate)
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <count>/<s|m|h>", value)
+	}
+
+	rate, err := strconv.Atoi(parts[0])
+	if err != nil || rate <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit count %q", parts[0])
+	}
+
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[parts[1]]
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit period %q", parts[1])
+	}
+
+	limit := Limit{Rate: rate, Period: period, Burst: rate}
+	// the Redis store counts in microseconds and a zero emission interval would divide by zero
+	if limit.emissionInterval() < time.Microsecond {
+		return Limit{}, fmt.Errorf("rate limit %q is too high, at most one request per microsecond is supported", value)
+	}
+	return limit, nil
+}
+
+// emissionInterval is the time it takes to earn back one request
+func (l Limit) emissionInterval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+// Result is the outcome of a single rate limit check
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining requests that can be made right now
+	Remaining int
+	// RetryAfter is how long to wait before the next request is allowed (zero when allowed)
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is completely refilled
+	ResetAfter time.Duration
+}
+
+// Store keeps the GCRA theoretical arrival time per key and applies the algorithm atomically
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// gcra applies the algorithm to the stored theoretical arrival time (tat).
+// It returns the result and the new tat to store when the request is allowed.
+func gcra(now, tat time.Time, limit Limit) (Result, time.Time) {
+	emission := limit.emissionInterval()
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(emission)
+	allowAt := newTAT.Add(-emission * time.Duration(limit.Burst))
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Limit:      limit.Rate,
+			Remaining:  0,
+			RetryAfter: allowAt.Sub(now),
+			ResetAfter: tat.Sub(now),
+		}, tat
+	}
+
+	return Result{
+		Allowed:    true,
+		Limit:      limit.Rate,
+		Remaining:  int(now.Sub(allowAt) / emission),
+		ResetAfter: newTAT.Sub(now),
+	}, newTAT
+}
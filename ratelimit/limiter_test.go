@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "100/m", want: Limit{Rate: 100, Period: time.Minute, Burst: 100}},
+		{value: "10/s", want: Limit{Rate: 10, Period: time.Second, Burst: 10}},
+		{value: " 1000/h ", want: Limit{Rate: 1000, Period: time.Hour, Burst: 1000}},
+		{value: "100", wantErr: true},
+		{value: "0/m", wantErr: true},
+		{value: "-1/m", wantErr: true},
+		{value: "abc/m", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "1000000/s", want: Limit{Rate: 1000000, Period: time.Second, Burst: 1000000}},
+		{value: "1000001/s", wantErr: true},
+		{value: "2000000000/s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLimit(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLimit(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	// one request every 100ms with bursts of 3
+	limit := Limit{Rate: 10, Period: time.Second, Burst: 3}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := time.Millisecond
+
+	// steps run in order against the same stored tat
+	steps := []struct {
+		name          string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{name: "first request", at: 0, wantAllowed: true, wantRemaining: 2, wantReset: 100 * ms},
+		{name: "second request in burst", at: 0, wantAllowed: true, wantRemaining: 1, wantReset: 200 * ms},
+		{name: "burst exhausted", at: 0, wantAllowed: true, wantRemaining: 0, wantReset: 300 * ms},
+		{name: "over burst", at: 0, wantAllowed: false, wantRetry: 100 * ms, wantReset: 300 * ms},
+		{name: "still limited", at: 50 * ms, wantAllowed: false, wantRetry: 50 * ms, wantReset: 250 * ms},
+		{name: "one emission later", at: 100 * ms, wantAllowed: true, wantRemaining: 0, wantReset: 300 * ms},
+		{name: "bucket refilled", at: time.Second, wantAllowed: true, wantRemaining: 2, wantReset: 100 * ms},
+	}
+
+	var tat time.Time
+	for _, step := range steps {
+		now := start.Add(step.at)
+		result, newTAT := gcra(now, tat, limit)
+
+		if result.Allowed != step.wantAllowed {
+			t.Fatalf("%s: allowed = %v, want %v", step.name, result.Allowed, step.wantAllowed)
+		}
+		if result.Limit != limit.Rate {
+			t.Errorf("%s: limit = %d, want %d", step.name, result.Limit, limit.Rate)
+		}
+		if result.Remaining != step.wantRemaining {
+			t.Errorf("%s: remaining = %d, want %d", step.name, result.Remaining, step.wantRemaining)
+		}
+		if result.RetryAfter != step.wantRetry {
+			t.Errorf("%s: retry after = %v, want %v", step.name, result.RetryAfter, step.wantRetry)
+		}
+		if result.ResetAfter != step.wantReset {
+			t.Errorf("%s: reset after = %v, want %v", step.name, result.ResetAfter, step.wantReset)
+		}
+
+		// stores only persist the tat of allowed requests
+		if result.Allowed {
+			tat = newTAT
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps rate limit state in process memory.
+// Keys whose bucket has fully refilled are evicted periodically.
+type MemoryStore struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+	stop chan struct{}
+}
+
+// NewMemoryStore creates an in-memory store that evicts idle keys every cleanupInterval
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		tats: make(map[string]time.Time),
+		stop: make(chan struct{}),
+	}
+	go s.cleanup(cleanupInterval)
+	return s
+}
+
+// Allow implements Store
+func (s *MemoryStore) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, tat := gcra(now, s.tats[key], limit)
+	if result.Allowed {
+		s.tats[key] = tat
+	}
+	return result, nil
+}
+
+// Close stops the eviction goroutine
+func (s *MemoryStore) Close() {
+	close(s.stop)
+}
+
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evictExpired(time.Now())
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// evictExpired removes keys whose theoretical arrival time has passed (bucket is full again)
+func (s *MemoryStore) evictExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, tat := range s.tats {
+		if tat.Before(now) {
+			delete(s.tats, key)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingStore simulates a store outage
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func newTestRouter(store Store, limit Limit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(NewLimiter(store).Middleware("test", limit, ByIP))
+	router.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return router
+}
+
+func get(router http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	limit := Limit{Rate: 2, Period: time.Minute, Burst: 2}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		wantStatus     int
+		wantRemaining  string
+		wantReset      string
+		wantRetryAfter string
+	}{
+		{name: "first request", remoteAddr: "192.0.2.1:1234", wantStatus: http.StatusOK, wantRemaining: "1", wantReset: "30"},
+		{name: "second request", remoteAddr: "192.0.2.1:1234", wantStatus: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+		{name: "limited", remoteAddr: "192.0.2.1:1234", wantStatus: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "60", wantRetryAfter: "30"},
+		{name: "other IP", remoteAddr: "192.0.2.2:1234", wantStatus: http.StatusOK, wantRemaining: "1", wantReset: "30"},
+	}
+
+	for _, store := range stores {
+		t.Run(store.name, func(t *testing.T) {
+			router := newTestRouter(store.new(t), limit)
+			for _, tt := range tests {
+				rec := get(router, tt.remoteAddr)
+
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+				}
+				headers := map[string]string{
+					"RateLimit-Limit":     "2",
+					"RateLimit-Remaining": tt.wantRemaining,
+					"RateLimit-Reset":     tt.wantReset,
+					"Retry-After":         tt.wantRetryAfter,
+				}
+				for name, want := range headers {
+					if got := rec.Header().Get(name); got != want {
+						t.Errorf("%s: %s = %q, want %q", tt.name, name, got, want)
+					}
+				}
+
+				if tt.wantStatus == http.StatusTooManyRequests {
+					var body map[string]string
+					if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+						t.Errorf("%s: body = %s, want JSON error", tt.name, rec.Body.String())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	router := newTestRouter(failingStore{}, Limit{Rate: 1, Period: time.Minute, Burst: 1})
+
+	for i := 0; i < 3; i++ {
+		rec := get(router, "192.0.2.1:1234")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+		if rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("request %d: RateLimit headers set without a store result", i+1)
+		}
+	}
+}
+
+func TestLimiterRoute(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	t.Cleanup(store.Close)
+	limiter := NewLimiter(store)
+	limits := Config{Routes: map[string]Limit{"login": {Rate: 1, Period: time.Minute, Burst: 1}}}
+	shared := limiter.Middleware("auth", Limit{Rate: 2, Period: time.Minute, Burst: 2}, ByIP)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/login", limiter.Route(limits, "login", ByIP, shared), ok)
+	router.GET("/refresh", limiter.Route(limits, "refresh", ByIP, shared), ok)
+	router.GET("/me", limiter.Route(limits, "me", ByIP, nil), ok)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantLimit  string
+	}{
+		{name: "configured route uses its own limit", path: "/login", wantStatus: http.StatusOK, wantLimit: "1"},
+		{name: "configured route is limited", path: "/login", wantStatus: http.StatusTooManyRequests, wantLimit: "1"},
+		{name: "other route falls back to the shared bucket", path: "/refresh", wantStatus: http.StatusOK, wantLimit: "2"},
+		{name: "shared bucket is not used by the configured route", path: "/refresh", wantStatus: http.StatusOK, wantLimit: "2"},
+		{name: "shared bucket exhausted", path: "/refresh", wantStatus: http.StatusTooManyRequests, wantLimit: "2"},
+		{name: "no fallback means no limit", path: "/me", wantStatus: http.StatusOK, wantLimit: ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != tt.wantLimit {
+			t.Errorf("%s: RateLimit-Limit = %q, want %q", tt.name, got, tt.wantLimit)
+		}
+	}
+}
+
+func TestByIPIgnoresUntrustedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatalf("SetTrustedProxies error: %v", err)
+	}
+	router.GET("/key", func(c *gin.Context) { c.String(http.StatusOK, ByIP(c)) })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "192.0.2.1:1234", want: "ip:192.0.2.1"},
+		{name: "spoofed header from client", remoteAddr: "192.0.2.1:1234", forwarded: "198.51.100.7", want: "ip:192.0.2.1"},
+		{name: "header from trusted proxy", remoteAddr: "10.0.0.1:1234", forwarded: "198.51.100.7", want: "ip:198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/key", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s: key = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// stores runs each store test against the in-memory store and the Redis store (on miniredis)
+var stores = []struct {
+	name string
+	new  func(t *testing.T) Store
+}{
+	{
+		name: "memory",
+		new: func(t *testing.T) Store {
+			store := NewMemoryStore(time.Minute)
+			t.Cleanup(store.Close)
+			return store
+		},
+	},
+	{
+		name: "redis",
+		new: func(t *testing.T) Store {
+			return NewRedisStore(newTestRedis(t), "ratelimit:")
+		},
+	},
+}
+
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestStoreAllow(t *testing.T) {
+	// three requests per hour: the clock barely moves during the test
+	limit := Limit{Rate: 3, Period: time.Hour, Burst: 3}
+	emission := limit.emissionInterval()
+
+	tests := []struct {
+		name          string
+		key           string
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{name: "first request", key: "a", wantAllowed: true, wantRemaining: 2},
+		{name: "second request", key: "a", wantAllowed: true, wantRemaining: 1},
+		{name: "last request in burst", key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "over limit", key: "a", wantAllowed: false, wantRemaining: 0},
+		{name: "denied request is not counted", key: "a", wantAllowed: false, wantRemaining: 0},
+		{name: "other key has its own bucket", key: "b", wantAllowed: true, wantRemaining: 2},
+	}
+
+	for _, store := range stores {
+		t.Run(store.name, func(t *testing.T) {
+			s := store.new(t)
+			for _, tt := range tests {
+				result, err := s.Allow(context.Background(), tt.key, limit)
+				if err != nil {
+					t.Fatalf("%s: Allow error: %v", tt.name, err)
+				}
+				if result.Allowed != tt.wantAllowed {
+					t.Fatalf("%s: allowed = %v, want %v", tt.name, result.Allowed, tt.wantAllowed)
+				}
+				if result.Limit != limit.Rate {
+					t.Errorf("%s: limit = %d, want %d", tt.name, result.Limit, limit.Rate)
+				}
+				if result.Remaining != tt.wantRemaining {
+					t.Errorf("%s: remaining = %d, want %d", tt.name, result.Remaining, tt.wantRemaining)
+				}
+
+				if tt.wantAllowed {
+					if result.RetryAfter != 0 {
+						t.Errorf("%s: retry after = %v, want 0", tt.name, result.RetryAfter)
+					}
+					continue
+				}
+				// the next request is allowed one emission interval after the first one
+				if result.RetryAfter <= emission-time.Second || result.RetryAfter > emission {
+					t.Errorf("%s: retry after = %v, want about %v", tt.name, result.RetryAfter, emission)
+				}
+				if result.ResetAfter <= limit.Period-time.Second || result.ResetAfter > limit.Period {
+					t.Errorf("%s: reset after = %v, want about %v", tt.name, result.ResetAfter, limit.Period)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisStoreExpiresKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisStore(client, "ratelimit:")
+
+	limit := Limit{Rate: 2, Period: time.Minute, Burst: 2}
+	if _, err := store.Allow(context.Background(), "ip:192.0.2.1", limit); err != nil {
+		t.Fatalf("Allow error: %v", err)
+	}
+
+	// the key lives until the bucket is full again so idle clients don't leak keys
+	ttl := server.TTL("ratelimit:ip:192.0.2.1")
+	if ttl <= 0 || ttl > limit.emissionInterval() {
+		t.Fatalf("ttl = %v, want (0, %v]", ttl, limit.emissionInterval())
+	}
+
+	server.FastForward(ttl)
+	if server.Exists("ratelimit:ip:192.0.2.1") {
+		t.Fatal("key still exists after its ttl")
+	}
+}
+
+func TestMemoryStoreEvictsRefilledKeys(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	t.Cleanup(store.Close)
+
+	limit := Limit{Rate: 2, Period: time.Minute, Burst: 2}
+	if _, err := store.Allow(context.Background(), "ip:192.0.2.1", limit); err != nil {
+		t.Fatalf("Allow error: %v", err)
+	}
+
+	store.evictExpired(time.Now())
+	if _, ok := store.tats["ip:192.0.2.1"]; !ok {
+		t.Fatal("key evicted before its bucket refilled")
+	}
+
+	store.evictExpired(time.Now().Add(limit.emissionInterval()))
+	if _, ok := store.tats["ip:192.0.2.1"]; ok {
+		t.Fatal("key not evicted after its bucket refilled")
+	}
+}
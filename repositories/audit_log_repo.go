@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"fmt"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository interface สำหรับบันทึกและอ่าน audit log
+type AuditLogRepository interface {
+	Create(entry *models.AuditLog) error
+	GetByUserID(userID uint, page, limit int) ([]*models.AuditLog, int64, error)
+}
+
+// auditLogRepository struct implements AuditLogRepository interface
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates new audit log repository instance
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก audit log
+func (r *auditLogRepository) Create(entry *models.AuditLog) error {
+	if err := r.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// GetByUserID ดึง audit log ที่เกี่ยวกับ user (ทั้งเป็นผู้ทำและผู้ถูกกระทำ) ล่าสุดก่อน
+func (r *auditLogRepository) GetByUserID(userID uint, page, limit int) ([]*models.AuditLog, int64, error) {
+	var entries []*models.AuditLog
+	var total int64
+
+	query := r.db.Model(&models.AuditLog{}).Where("user_id = ? OR actor_id = ?", userID, userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvitationNotFound ไม่พบคำเชิญ
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// InvitationRepository interface สำหรับคำเชิญเข้า organization
+type InvitationRepository interface {
+	Create(invitation *models.Invitation) error
+	GetByID(organizationID, id uint) (*models.Invitation, error)
+	GetByTokenHash(tokenHash string) (*models.Invitation, error)
+	GetPendingByEmail(organizationID uint, email string) (*models.Invitation, error)
+	GetByOrganizationID(organizationID uint) ([]*models.Invitation, error)
+	ExpirePending(organizationID uint) error
+	Reissue(id, invitedByID uint, tokenHash string, expiresAt time.Time) error
+	Revoke(organizationID, id uint) (bool, error)
+	Accept(invitation *models.Invitation, userID uint) (bool, error)
+	Delete(id uint) error
+}
+
+// invitationRepository struct implements InvitationRepository interface
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates new invitation repository instance
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{
+		db: db,
+	}
+}
+
+// Create บันทึกคำเชิญใหม่
+func (r *invitationRepository) Create(invitation *models.Invitation) error {
+	if err := r.db.Create(invitation).Error; err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+// GetByID หาคำเชิญของ organization
+func (r *invitationRepository) GetByID(organizationID, id uint) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	if err := r.db.Where("id = ? AND organization_id = ?", id, organizationID).First(invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: id %d", ErrInvitationNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+// GetByTokenHash หาคำเชิญจาก hash ของ token ในลิงก์ พร้อม organization
+func (r *invitationRepository) GetByTokenHash(tokenHash string) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	if err := r.db.Preload("Organization").Where("token_hash = ?", tokenHash).First(invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+// GetPendingByEmail หาคำเชิญที่ยังรอตอบรับของ email ใน organization
+func (r *invitationRepository) GetPendingByEmail(organizationID uint, email string) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	if err := r.db.Where("organization_id = ? AND email = ? AND status = ? AND expires_at > ?",
+		organizationID, email, models.InvitationPending, time.Now()).
+		First(invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+// GetByOrganizationID ดึงคำเชิญทั้งหมดของ organization (ล่าสุดก่อน)
+func (r *invitationRepository) GetByOrganizationID(organizationID uint) ([]*models.Invitation, error) {
+	var invitations []*models.Invitation
+	if err := r.db.Where("organization_id = ?", organizationID).
+		Order("created_at DESC").
+		Find(&invitations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// ExpirePending เปลี่ยนคำเชิญที่เลยเวลาแล้วเป็น expired
+func (r *invitationRepository) ExpirePending(organizationID uint) error {
+	if err := r.db.Model(&models.Invitation{}).
+		Where("organization_id = ? AND status = ? AND expires_at <= ?", organizationID, models.InvitationPending, time.Now()).
+		Update("status", models.InvitationExpired).Error; err != nil {
+		return fmt.Errorf("failed to expire invitations: %w", err)
+	}
+	return nil
+}
+
+// Reissue ออก token และวันหมดอายุใหม่ให้คำเชิญที่ยังรอหรือหมดอายุ (token เดิมใช้ไม่ได้)
+func (r *invitationRepository) Reissue(id, invitedByID uint, tokenHash string, expiresAt time.Time) error {
+	result := r.db.Model(&models.Invitation{}).
+		Where("id = ? AND status IN ?", id, []string{models.InvitationPending, models.InvitationExpired}).
+		Updates(map[string]interface{}{
+			"token_hash":    tokenHash,
+			"expires_at":    expiresAt,
+			"status":        models.InvitationPending,
+			"invited_by_id": invitedByID,
+			"last_sent_at":  time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reissue invitation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// Revoke ยกเลิกคำเชิญที่ยังรอตอบรับ คืน false ถ้าคำเชิญไม่ได้อยู่ในสถานะ pending
+func (r *invitationRepository) Revoke(organizationID, id uint) (bool, error) {
+	result := r.db.Model(&models.Invitation{}).
+		Where("id = ? AND organization_id = ? AND status = ?", id, organizationID, models.InvitationPending).
+		Updates(map[string]interface{}{
+			"status":     models.InvitationRevoked,
+			"revoked_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke invitation: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Accept ตอบรับคำเชิญแบบ atomic และเพิ่ม user เข้า organization ใน transaction เดียว
+// คืน false ถ้าคำเชิญถูกใช้ ยกเลิก หรือหมดอายุไปแล้ว (user ที่เป็นสมาชิกอยู่แล้วคง role เดิม)
+func (r *invitationRepository) Accept(invitation *models.Invitation, userID uint) (bool, error) {
+	accepted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND status = ? AND expires_at > ?", invitation.ID, models.InvitationPending, now).
+			Updates(map[string]interface{}{
+				"status":           models.InvitationAccepted,
+				"accepted_user_id": userID,
+				"accepted_at":      now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to accept invitation: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		membership := &models.Membership{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         userID,
+			Role:           invitation.Role,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(membership).Error; err != nil {
+			return fmt.Errorf("failed to create membership: %w", err)
+		}
+		accepted = true
+		return nil
+	})
+	return accepted, err
+}
+
+// Delete ลบคำเชิญ (ใช้ยกเลิกคำเชิญที่ส่ง email ไม่สำเร็จ)
+func (r *invitationRepository) Delete(id uint) error {
+	if err := r.db.Delete(&models.Invitation{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+	return nil
+}
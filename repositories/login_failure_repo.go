@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginFailureRepository interface สำหรับนับ login ที่ผิดต่อ IP
+type LoginFailureRepository interface {
+	GetByIP(ip string) (*models.LoginIPFailure, error)
+	RecordFailure(ip string, now time.Time, window time.Duration) (int, error)
+	LockIP(ip string, until time.Time) error
+	DeleteStale(before time.Time) error
+}
+
+// loginFailureRepository struct implements LoginFailureRepository interface
+type loginFailureRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginFailureRepository creates new login failure repository instance
+func NewLoginFailureRepository(db *gorm.DB) LoginFailureRepository {
+	return &loginFailureRepository{
+		db: db,
+	}
+}
+
+// GetByIP ดึงสถานะ login ผิดของ IP คืน nil ถ้าไม่เคยผิด
+func (r *loginFailureRepository) GetByIP(ip string) (*models.LoginIPFailure, error) {
+	failure := &models.LoginIPFailure{}
+	if err := r.db.Where("ip = ?", ip).First(failure).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+	return failure, nil
+}
+
+// RecordFailure เพิ่มจำนวน login ผิดของ IP แบบ atomic คืนจำนวนล่าสุด
+// ถ้าครั้งล่าสุดเก่ากว่า window จะเริ่มนับใหม่
+func (r *loginFailureRepository) RecordFailure(ip string, now time.Time, window time.Duration) (int, error) {
+	failure := &models.LoginIPFailure{
+		IP:             ip,
+		FailedAttempts: 1,
+		LastFailedAt:   now,
+	}
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "ip"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failed_attempts": gorm.Expr("CASE WHEN login_ip_failures.last_failed_at < ? THEN 1 ELSE login_ip_failures.failed_attempts + 1 END", now.Add(-window)),
+				"last_failed_at":  now,
+				"updated_at":      now,
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failed_attempts"}}},
+	).Create(failure).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return failure.FailedAttempts, nil
+}
+
+// LockIP ล็อค IP ไม่ให้ login ด้วย password จนถึงเวลาที่กำหนด
+func (r *loginFailureRepository) LockIP(ip string, until time.Time) error {
+	if err := r.db.Model(&models.LoginIPFailure{}).Where("ip = ?", ip).Update("locked_until", until).Error; err != nil {
+		return fmt.Errorf("failed to lock ip: %w", err)
+	}
+	return nil
+}
+
+// DeleteStale ลบ IP ที่ไม่ได้ login ผิดและไม่ถูกล็อคตั้งแต่ before
+func (r *loginFailureRepository) DeleteStale(before time.Time) error {
+	err := r.db.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&models.LoginIPFailure{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete stale login failures: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrMembershipNotFound user ไม่ได้เป็นสมาชิกของ organization
+var ErrMembershipNotFound = errors.New("membership not found")
+
+// MembershipRepository interface สำหรับสมาชิกของ organizations
+type MembershipRepository interface {
+	Create(membership *models.Membership) error
+	Get(organizationID, userID uint) (*models.Membership, error)
+	GetDefault(userID uint) (*models.Membership, error)
+	GetByUserID(userID uint) ([]*models.Membership, error)
+	GetByOrganizationID(organizationID uint) ([]*models.Membership, error)
+	CountByRole(organizationID uint, role string) (int64, error)
+	UpdateRole(organizationID, userID uint, role string) error
+	Delete(organizationID, userID uint) error
+}
+
+// membershipRepository struct implements MembershipRepository interface
+type membershipRepository struct {
+	db *gorm.DB
+}
+
+// NewMembershipRepository creates new membership repository instance
+func NewMembershipRepository(db *gorm.DB) MembershipRepository {
+	return &membershipRepository{
+		db: db,
+	}
+}
+
+// Create เพิ่ม user เข้า organization
+func (r *membershipRepository) Create(membership *models.Membership) error {
+	if err := r.db.Create(membership).Error; err != nil {
+		return fmt.Errorf("failed to create membership: %w", err)
+	}
+	return nil
+}
+
+// Get หา membership ของ user ใน organization
+func (r *membershipRepository) Get(organizationID, userID uint) (*models.Membership, error) {
+	membership := &models.Membership{}
+	if err := r.db.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMembershipNotFound
+		}
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	return membership, nil
+}
+
+// GetDefault membership แรกของ user ใช้เป็น organization เริ่มต้นตอน login
+func (r *membershipRepository) GetDefault(userID uint) (*models.Membership, error) {
+	membership := &models.Membership{}
+	if err := r.db.Where("user_id = ?", userID).Order("created_at ASC, id ASC").First(membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMembershipNotFound
+		}
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	return membership, nil
+}
+
+// GetByUserID ดึง organizations ที่ user เป็นสมาชิก
+func (r *membershipRepository) GetByUserID(userID uint) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+	if err := r.db.Preload("Organization").
+		Where("user_id = ?", userID).
+		Order("created_at ASC, id ASC").
+		Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("failed to get memberships: %w", err)
+	}
+	return memberships, nil
+}
+
+// GetByOrganizationID ดึงสมาชิกทั้งหมดของ organization
+func (r *membershipRepository) GetByOrganizationID(organizationID uint) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+	if err := r.db.Preload("User").
+		Where("organization_id = ?", organizationID).
+		Order("created_at ASC, id ASC").
+		Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	return memberships, nil
+}
+
+// CountByRole นับสมาชิกของ organization ที่มี role ที่กำหนด
+func (r *membershipRepository) CountByRole(organizationID uint, role string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND role = ?", organizationID, role).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateRole เปลี่ยน role ของสมาชิก
+func (r *membershipRepository) UpdateRole(organizationID, userID uint, role string) error {
+	result := r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update membership: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
+
+// Delete นำ user ออกจาก organization
+func (r *membershipRepository) Delete(organizationID, userID uint) error {
+	result := r.db.Where("organization_id = ? AND user_id = ?", organizationID, userID).Delete(&models.Membership{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete membership: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrOAuthClientNotFound ไม่พบ OAuth client
+var ErrOAuthClientNotFound = errors.New("oauth client not found")
+
+// OAuthClientRepository interface สำหรับ OAuth clients ที่ใช้ client_credentials grant
+type OAuthClientRepository interface {
+	Create(client *models.OAuthClient) error
+	GetByID(id uint) (*models.OAuthClient, error)
+	GetByClientID(clientID string) (*models.OAuthClient, error)
+	GetAll() ([]*models.OAuthClient, error)
+	UpdateSecret(id uint, secretHash string) error
+	ExpireSecretsByCreator(userID uint) (int64, error)
+	RecordUse(id uint) error
+	Delete(id uint) error
+}
+
+// oauthClientRepository struct implements OAuthClientRepository interface
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository creates new OAuth client repository instance
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &oauthClientRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก client ใหม่
+func (r *oauthClientRepository) Create(client *models.OAuthClient) error {
+	if err := r.db.Create(client).Error; err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return nil
+}
+
+// GetByID หา client ด้วย ID
+func (r *oauthClientRepository) GetByID(id uint) (*models.OAuthClient, error) {
+	client := &models.OAuthClient{}
+	if err := r.db.First(client, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: id %d", ErrOAuthClientNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	return client, nil
+}
+
+// GetByClientID หา client ด้วย client_id
+func (r *oauthClientRepository) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	client := &models.OAuthClient{}
+	if err := r.db.Where("client_id = ?", clientID).First(client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	return client, nil
+}
+
+// GetAll ดึง clients ทั้งหมด
+func (r *oauthClientRepository) GetAll() ([]*models.OAuthClient, error) {
+	var clients []*models.OAuthClient
+	if err := r.db.Order("created_at ASC").Find(&clients).Error; err != nil {
+		return nil, fmt.Errorf("failed to get oauth clients: %w", err)
+	}
+	return clients, nil
+}
+
+// UpdateSecret เปลี่ยน hash ของ client_secret
+func (r *oauthClientRepository) UpdateSecret(id uint, secretHash string) error {
+	result := r.db.Model(&models.OAuthClient{}).Where("id = ?", id).Update("secret_hash", secretHash)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update oauth client secret: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id %d", ErrOAuthClientNotFound, id)
+	}
+	return nil
+}
+
+// ExpireSecretsByCreator ล้าง secret ของ clients ที่ user สร้าง (ขอ token ไม่ได้จนกว่าจะออก secret ใหม่)
+// คืนจำนวน clients ที่ถูกล้าง secret
+func (r *oauthClientRepository) ExpireSecretsByCreator(userID uint) (int64, error) {
+	result := r.db.Model(&models.OAuthClient{}).
+		Where("created_by = ? AND secret_hash <> ''", userID).
+		Update("secret_hash", "")
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to expire oauth client secrets: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RecordUse บันทึกเวลาที่ client ขอ token ล่าสุด
+func (r *oauthClientRepository) RecordUse(id uint) error {
+	if err := r.db.Model(&models.OAuthClient{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to record oauth client use: %w", err)
+	}
+	return nil
+}
+
+// Delete ลบ client (token ที่ออกไปแล้วใช้ได้จนหมดอายุ)
+func (r *oauthClientRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.OAuthClient{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id %d", ErrOAuthClientNotFound, id)
+	}
+	return nil
+}
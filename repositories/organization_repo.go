@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrOrganizationNotFound ไม่พบ organization
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+// OrganizationRepository interface สำหรับ organizations (tenants)
+type OrganizationRepository interface {
+	CreateWithOwner(organization *models.Organization, ownerID uint) error
+	GetByID(id uint) (*models.Organization, error)
+	GetBySlug(slug string) (*models.Organization, error)
+}
+
+// organizationRepository struct implements OrganizationRepository interface
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository creates new organization repository instance
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+// CreateWithOwner สร้าง organization พร้อม membership owner ของผู้สร้างใน transaction เดียว
+func (r *organizationRepository) CreateWithOwner(organization *models.Organization, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return fmt.Errorf("failed to create organization: %w", err)
+		}
+		membership := &models.Membership{
+			OrganizationID: organization.ID,
+			UserID:         ownerID,
+			Role:           models.OrgRoleOwner,
+		}
+		if err := tx.Create(membership).Error; err != nil {
+			return fmt.Errorf("failed to create membership: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByID หา organization ด้วย ID
+func (r *organizationRepository) GetByID(id uint) (*models.Organization, error) {
+	organization := &models.Organization{}
+	if err := r.db.First(organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: id %d", ErrOrganizationNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return organization, nil
+}
+
+// GetBySlug หา organization ด้วย slug
+func (r *organizationRepository) GetBySlug(slug string) (*models.Organization, error) {
+	organization := &models.Organization{}
+	if err := r.db.Where("slug = ?", slug).First(organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: slug %s", ErrOrganizationNotFound, slug)
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return organization, nil
+}
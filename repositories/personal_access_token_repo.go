@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrPersonalAccessTokenNotFound ไม่พบ token หรือ token ถูกเพิกถอนแล้ว
+var ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+
+// PersonalAccessTokenRepository interface สำหรับ personal access tokens ของ user
+type PersonalAccessTokenRepository interface {
+	Create(token *models.PersonalAccessToken) error
+	GetByHash(tokenHash string) (*models.PersonalAccessToken, error)
+	GetByUserID(userID uint) ([]*models.PersonalAccessToken, error)
+	RecordUse(id uint, ip string) error
+	Revoke(userID, id uint) error
+	RevokeAllForUser(userID uint) (int64, error)
+}
+
+// personalAccessTokenRepository struct implements PersonalAccessTokenRepository interface
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewPersonalAccessTokenRepository creates new personal access token repository instance
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก token ใหม่
+func (r *personalAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create personal access token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash หา token ที่ยังไม่ถูกเพิกถอนด้วย hash (การตรวจวันหมดอายุทำใน service)
+func (r *personalAccessTokenRepository) GetByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	if err := r.db.Where("token_hash = ? AND revoked_at IS NULL", tokenHash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonalAccessTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get personal access token: %w", err)
+	}
+	return token, nil
+}
+
+// GetByUserID ดึง tokens ที่ยังไม่ถูกเพิกถอนของ user (ใหม่สุดก่อน)
+func (r *personalAccessTokenRepository) GetByUserID(userID uint) ([]*models.PersonalAccessToken, error) {
+	var tokens []*models.PersonalAccessToken
+	if err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to get personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RecordUse บันทึกเวลาและ IP ที่ใช้ token ล่าสุด
+func (r *personalAccessTokenRepository) RecordUse(id uint, ip string) error {
+	if err := r.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": ip,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to record personal access token use: %w", err)
+	}
+	return nil
+}
+
+// Revoke เพิกถอน token ของ user
+func (r *personalAccessTokenRepository) Revoke(userID, id uint) error {
+	result := r.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke personal access token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id %d", ErrPersonalAccessTokenNotFound, id)
+	}
+	return nil
+}
+
+// RevokeAllForUser เพิกถอน tokens ทั้งหมดที่ยังใช้ได้ของ user คืนจำนวน tokens ที่ถูกเพิกถอน
+func (r *personalAccessTokenRepository) RevokeAllForUser(userID uint) (int64, error) {
+	result := r.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke personal access tokens: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenNotFound ถูกคืนเมื่อไม่พบ refresh token
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// RefreshTokenRepository interface สำหรับจัดการ refresh tokens
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(tokenHash string) (*models.RefreshToken, error)
+	MarkUsed(id uint) (bool, error)
+	MarkFamilyUsed(familyID string) error
+	GetIssuedSince(familyID string, since time.Time) ([]*models.RefreshToken, error)
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID uint) error
+}
+
+// refreshTokenRepository struct implements RefreshTokenRepository interface
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates new refresh token repository instance
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก refresh token ใหม่
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash หา refresh token ด้วย hash
+func (r *refreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	if err := r.db.Where("token_hash = ?", tokenHash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// MarkUsed ตั้งค่า used_at แบบ atomic คืน false ถ้า token ถูกใช้ไปแล้ว
+func (r *refreshTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark refresh token as used: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkFamilyUsed ตั้งค่า used_at ให้ refresh tokens ที่ยังไม่ได้ใช้ของ family (ใช้ซ้ำจะถือเป็น reuse)
+func (r *refreshTokenRepository) MarkFamilyUsed(familyID string) error {
+	if err := r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND used_at IS NULL", familyID).
+		Update("used_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to mark refresh tokens as used: %w", err)
+	}
+	return nil
+}
+
+// GetIssuedSince ดึง refresh tokens ของ family ที่ออกหลังเวลาที่กำหนด (access token คู่กันอาจยังไม่หมดอายุ)
+func (r *refreshTokenRepository) GetIssuedSince(familyID string, since time.Time) ([]*models.RefreshToken, error) {
+	var tokens []*models.RefreshToken
+	if err := r.db.Where("family_id = ? AND created_at > ?", familyID, since).Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to get refresh tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeFamily เพิกถอน refresh token ทั้ง family
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	if err := r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser เพิกถอน refresh token ทั้งหมดของ user
+func (r *refreshTokenRepository) RevokeAllForUser(userID uint) error {
+	if err := r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrRoleNotFound ถูกคืนเมื่อไม่พบ role
+var ErrRoleNotFound = errors.New("role not found")
+
+// RoleRepository interface สำหรับ roles และ permissions
+type RoleRepository interface {
+	GetByName(name string) (*models.Role, error)
+	GetAll() ([]*models.Role, error)
+	GetPermissionNames(roleName string) ([]string, error)
+	SetRequireMFA(name string, required bool) error
+}
+
+// roleRepository struct implements RoleRepository interface
+type roleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates new role repository instance
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{
+		db: db,
+	}
+}
+
+// GetByName หา role ด้วยชื่อพร้อม permissions
+func (r *roleRepository) GetByName(name string) (*models.Role, error) {
+	role := &models.Role{}
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	return role, nil
+}
+
+// GetAll ดึง roles ทั้งหมดพร้อม permissions
+func (r *roleRepository) GetAll() ([]*models.Role, error) {
+	var roles []*models.Role
+	if err := r.db.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetPermissionNames ดึงชื่อ permissions ของ role
+func (r *roleRepository) GetPermissionNames(roleName string) ([]string, error) {
+	role, err := r.GetByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names, nil
+}
+
+// SetRequireMFA กำหนดว่า user ใน role ต้องเปิด 2FA หรือไม่
+func (r *roleRepository) SetRequireMFA(name string, required bool) error {
+	result := r.db.Model(&models.Role{}).Where("name = ?", name).Update("require_mfa", required)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update role MFA policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+	}
+	return nil
+}
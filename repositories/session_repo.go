@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionNotFound ไม่พบ session หรือ session ถูกปิดไปแล้ว
+var ErrSessionNotFound = errors.New("session not found")
+
+// activeSessionCondition session ที่ยังมี refresh token ล่าสุดของ family ใช้ได้อยู่
+// (ไม่ถูกเพิกถอนจาก logout, ปิด session, logout ทุกอุปกรณ์ และยังไม่หมดอายุ)
+const activeSessionCondition = `EXISTS (
+	SELECT 1 FROM refresh_tokens
+	WHERE refresh_tokens.family_id = sessions.family_id
+		AND refresh_tokens.used_at IS NULL
+		AND refresh_tokens.revoked_at IS NULL
+		AND refresh_tokens.expires_at > ?
+)`
+
+// SessionRepository interface สำหรับ sessions (อุปกรณ์ที่ user login อยู่)
+type SessionRepository interface {
+	Create(session *models.Session) error
+	GetByFamilyID(familyID string) (*models.Session, error)
+	GetActiveByUserID(userID uint) ([]*models.Session, error)
+	GetActiveByID(userID, id uint) (*models.Session, error)
+	Touch(id uint, ip, userAgent string) error
+	TouchIfIdle(id uint, ip, userAgent string, idle time.Duration) error
+	SetOrganization(id uint, organizationID *uint) error
+}
+
+// sessionRepository struct implements SessionRepository interface
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates new session repository instance
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก session ใหม่
+func (r *sessionRepository) Create(session *models.Session) error {
+	if err := r.db.Create(session).Error; err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetByFamilyID หา session ของ refresh token family
+func (r *sessionRepository) GetByFamilyID(familyID string) (*models.Session, error) {
+	session := &models.Session{}
+	if err := r.db.Where("family_id = ?", familyID).First(session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// GetActiveByUserID ดึง sessions ที่ยังใช้งานได้ของ user (ใช้ล่าสุดก่อน)
+func (r *sessionRepository) GetActiveByUserID(userID uint) ([]*models.Session, error) {
+	var sessions []*models.Session
+	if err := r.db.Where("user_id = ?", userID).
+		Where(activeSessionCondition, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetActiveByID หา session ที่ยังใช้งานได้ของ user
+func (r *sessionRepository) GetActiveByID(userID, id uint) (*models.Session, error) {
+	session := &models.Session{}
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).
+		Where(activeSessionCondition, time.Now()).
+		First(session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: id %d", ErrSessionNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// Touch อัพเดทเวลา IP และ user agent ล่าสุดของ session
+func (r *sessionRepository) Touch(id uint, ip, userAgent string) error {
+	if err := r.db.Model(&models.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_seen_at": time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// TouchIfIdle อัพเดทเวลา IP และ user agent ล่าสุดของ session เฉพาะเมื่อไม่ได้อัพเดทมานานกว่า idle
+// (หลาย instance ของ server จึงไม่เขียน session เดียวกันซ้ำทุก request)
+func (r *sessionRepository) TouchIfIdle(id uint, ip, userAgent string, idle time.Duration) error {
+	now := time.Now()
+	if err := r.db.Model(&models.Session{}).
+		Where("id = ? AND last_seen_at < ?", id, now.Add(-idle)).
+		Updates(map[string]interface{}{
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_seen_at": now,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// SetOrganization เปลี่ยน organization ที่ใช้งานอยู่ของ session
+func (r *sessionRepository) SetOrganization(id uint, organizationID *uint) error {
+	if err := r.db.Model(&models.Session{}).
+		Where("id = ?", id).
+		Update("organization_id", organizationID).Error; err != nil {
+		return fmt.Errorf("failed to update session organization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRevocationRepository interface สำหรับเก็บรายการ access token ที่ถูกเพิกถอน
+type TokenRevocationRepository interface {
+	RevokeToken(token *models.RevokedToken) error
+	IsTokenRevoked(jti string) (bool, error)
+	RevokeAllForUser(userID uint, before time.Time) error
+	GetUserRevocation(userID uint) (*models.UserTokenRevocation, error)
+	DeleteExpired() error
+}
+
+// tokenRevocationRepository struct implements TokenRevocationRepository interface
+type tokenRevocationRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRevocationRepository creates new token revocation repository instance
+func NewTokenRevocationRepository(db *gorm.DB) TokenRevocationRepository {
+	return &tokenRevocationRepository{
+		db: db,
+	}
+}
+
+// RevokeToken บันทึก jti ที่ถูกเพิกถอน (ซ้ำได้ไม่ error)
+func (r *tokenRevocationRepository) RevokeToken(token *models.RevokedToken) error {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error; err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked ตรวจสอบว่า jti ถูกเพิกถอนหรือไม่
+func (r *tokenRevocationRepository) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RevokeAllForUser บันทึกเวลาที่ token ทั้งหมดของ user ที่ออกก่อนหน้านั้นใช้ไม่ได้
+func (r *tokenRevocationRepository) RevokeAllForUser(userID uint, before time.Time) error {
+	revocation := &models.UserTokenRevocation{
+		UserID:        userID,
+		RevokedBefore: before,
+	}
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(revocation).Error; err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+// GetUserRevocation ดึงเวลาเพิกถอน token ของ user คืน nil ถ้าไม่เคยเพิกถอน
+func (r *tokenRevocationRepository) GetUserRevocation(userID uint) (*models.UserTokenRevocation, error) {
+	revocation := &models.UserTokenRevocation{}
+	if err := r.db.Where("user_id = ?", userID).First(revocation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user token revocation: %w", err)
+	}
+	return revocation, nil
+}
+
+// DeleteExpired ลบ jti ที่หมดอายุไปแล้ว (token หมดอายุเองไม่ต้องเก็บต่อ)
+func (r *tokenRevocationRepository) DeleteExpired() error {
+	if err := r.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+	return nil
+}
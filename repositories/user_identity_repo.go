@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrUserIdentityNotFound ไม่พบ identity ที่ผูกไว้
+var ErrUserIdentityNotFound = errors.New("linked identity not found")
+
+// UserIdentityRepository interface สำหรับบัญชี OIDC provider ที่ผูกกับ user
+type UserIdentityRepository interface {
+	Create(identity *models.UserIdentity) error
+	CreateWithUser(user *models.User, identity *models.UserIdentity) error
+	CreateAndResetCredentials(identity *models.UserIdentity) error
+	GetByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+	GetByUserID(userID uint) ([]*models.UserIdentity, error)
+	CountByUserID(userID uint) (int64, error)
+	RecordLogin(id uint, email string, emailVerified bool) error
+	Delete(userID, id uint) error
+}
+
+// userIdentityRepository struct implements UserIdentityRepository interface
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository creates new user identity repository instance
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{
+		db: db,
+	}
+}
+
+// Create ผูก identity กับ user ที่มีอยู่แล้ว
+func (r *userIdentityRepository) Create(identity *models.UserIdentity) error {
+	if err := r.db.Create(identity).Error; err != nil {
+		return fmt.Errorf("failed to create user identity: %w", err)
+	}
+	return nil
+}
+
+// CreateWithUser สร้าง user ใหม่พร้อม identity ใน transaction เดียว
+func (r *userIdentityRepository) CreateWithUser(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		identity.UserID = user.ID
+		if err := tx.Create(identity).Error; err != nil {
+			return fmt.Errorf("failed to create user identity: %w", err)
+		}
+		return nil
+	})
+}
+
+// CreateAndResetCredentials ผูก identity กับ user และลบวิธี login อื่นทั้งหมดของ user ใน transaction เดียว
+// (password, TOTP, รหัสสำรอง, passkeys, identities อื่น และ personal access tokens)
+func (r *userIdentityRepository) CreateAndResetCredentials(identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		userID := identity.UserID
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"password_hash":       "",
+			"totp_secret":         "",
+			"totp_enabled_at":     nil,
+			"totp_last_used_step": 0,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to reset user credentials: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.WebAuthnCredential{}).Error; err != nil {
+			return fmt.Errorf("failed to delete passkeys: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user identities: %w", err)
+		}
+		if err := tx.Model(&models.PersonalAccessToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to revoke personal access tokens: %w", err)
+		}
+		if err := tx.Create(identity).Error; err != nil {
+			return fmt.Errorf("failed to create user identity: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByProviderSubject หา identity จาก provider และ subject ของ id_token
+func (r *userIdentityRepository) GetByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserIdentityNotFound
+		}
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// GetByUserID ดึง identities ทั้งหมดของ user
+func (r *userIdentityRepository) GetByUserID(userID uint) ([]*models.UserIdentity, error) {
+	var identities []*models.UserIdentity
+	if err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user identities: %w", err)
+	}
+	return identities, nil
+}
+
+// CountByUserID นับ identities ของ user
+func (r *userIdentityRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count user identities: %w", err)
+	}
+	return count, nil
+}
+
+// RecordLogin อัพเดท email ล่าสุดจาก provider และเวลาที่ login
+func (r *userIdentityRepository) RecordLogin(id uint, email string, emailVerified bool) error {
+	err := r.db.Model(&models.UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":          email,
+		"email_verified": emailVerified,
+		"last_login_at":  time.Now(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update user identity: %w", err)
+	}
+	return nil
+}
+
+// Delete ยกเลิกการผูก identity ของ user (ลบของคนอื่นไม่ได้)
+func (r *userIdentityRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete user identity: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserIdentityNotFound
+	}
+	return nil
+}
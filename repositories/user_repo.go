@@ -3,23 +3,28 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"collp-backend/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrUserNotFound ถูกคืนเมื่อไม่พบ user ในฐานข้อมูล
+var ErrUserNotFound = errors.New("user not found")
+
 // UserRepository interface สำหรับ User CRUD operations
 type UserRepository interface {
 	// Create operations
 	Create(user *models.User) error
-	CreateIfNotExists(email, googleID string, user *models.User) (*models.User, error)
+	CreateInOrganization(user *models.User, membership *models.Membership) error
 
 	// Read operations
 	GetByID(id uint) (*models.User, error)
+	GetByIDInOrganization(organizationID, id uint) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
-	GetByGoogleID(googleID string) (*models.User, error)
-	GetAll(page, limit int) ([]*models.User, int64, error)
+	GetAll(organizationID uint, page, limit int) ([]*models.User, int64, error)
 	GetActive() ([]*models.User, error)
 
 	// Update operations
@@ -28,19 +33,27 @@ type UserRepository interface {
 	UpdateStatus(id uint, isActive bool) error
 	UpdateAvatar(id uint, avatarURL string) error
 
+	// Login lockout operations
+	RecordFailedLogin(id uint, now time.Time, window time.Duration) (int, error)
+	LockUntil(id uint, until time.Time) error
+	ResetFailedLogins(id uint) error
+
+	// TOTP operations
+	RecordTOTPStep(id uint, step int64) (bool, error)
+
 	// Delete operations
 	Delete(id uint) error     // Soft delete
 	HardDelete(id uint) error // Hard delete
 	Restore(id uint) error    // Restore soft deleted
 
 	// Search operations
-	Search(keyword string, page, limit int) ([]*models.User, int64, error)
+	Search(organizationID uint, keyword string, page, limit int) ([]*models.User, int64, error)
 
 	// Utility operations
 	Exists(id uint) (bool, error)
 	ExistsByEmail(email string) (bool, error)
-	Count() (int64, error)
-	CountActive() (int64, error)
+	Count(organizationID uint) (int64, error)
+	CountActive(organizationID uint) (int64, error)
 }
 
 // userRepository struct implements UserRepository interface
@@ -63,28 +76,18 @@ func (r *userRepository) Create(user *models.User) error {
 	return nil
 }
 
-// CreateIfNotExists สร้าง user ใหม่ถ้ายังไม่มี
-func (r *userRepository) CreateIfNotExists(email, googleID string, user *models.User) (*models.User, error) {
-	// ตรวจสอบว่ามี user อยู่แล้วหรือไม่
-	existingUser := &models.User{}
-
-	// หา user ด้วย email หรือ google_id
-	err := r.db.Where("email = ? OR google_id = ?", email, googleID).First(existingUser).Error
-	if err == nil {
-		// User มีอยู่แล้ว return user เดิม
-		return existingUser, nil
-	}
-
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("failed to check existing user: %w", err)
-	}
-
-	// User ไม่มี สร้างใหม่
-	if err := r.Create(user); err != nil {
-		return nil, err
-	}
-
-	return user, nil
+// CreateInOrganization สร้าง user ใหม่และเพิ่มเป็นสมาชิกของ organization ใน transaction เดียว
+func (r *userRepository) CreateInOrganization(user *models.User, membership *models.Membership) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		membership.UserID = user.ID
+		if err := tx.Create(membership).Error; err != nil {
+			return fmt.Errorf("failed to create membership: %w", err)
+		}
+		return nil
+	})
 }
 
 // GetByID หา user ด้วย ID
@@ -92,7 +95,7 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	user := &models.User{}
 	if err := r.db.First(user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user with id %d not found", id)
+			return nil, fmt.Errorf("%w: id %d", ErrUserNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -104,32 +107,38 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	if err := r.db.Where("email = ?", email).First(user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user with email %s not found", email)
+			return nil, fmt.Errorf("%w: email %s", ErrUserNotFound, email)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	return user, nil
 }
 
-// GetByGoogleID หา user ด้วย Google ID
-func (r *userRepository) GetByGoogleID(googleID string) (*models.User, error) {
+// inOrganization จำกัด query ของ users ให้เหลือเฉพาะสมาชิกของ organization
+func (r *userRepository) inOrganization(organizationID uint) *gorm.DB {
+	return r.db.Model(&models.User{}).
+		Joins("JOIN memberships ON memberships.user_id = users.id AND memberships.organization_id = ?", organizationID)
+}
+
+// GetByIDInOrganization หา user ด้วย ID เฉพาะสมาชิกของ organization
+func (r *userRepository) GetByIDInOrganization(organizationID, id uint) (*models.User, error) {
 	user := &models.User{}
-	if err := r.db.Where("google_id = ?", googleID).First(user).Error; err != nil {
+	if err := r.inOrganization(organizationID).Select("users.*").Where("users.id = ?", id).First(user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user with google_id %s not found", googleID)
+			return nil, fmt.Errorf("%w: id %d in organization %d", ErrUserNotFound, id, organizationID)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	return user, nil
 }
 
-// GetAll ดึง users ทั้งหมดแบบ pagination
-func (r *userRepository) GetAll(page, limit int) ([]*models.User, int64, error) {
+// GetAll ดึง users ที่เป็นสมาชิกของ organization แบบ pagination
+func (r *userRepository) GetAll(organizationID uint, page, limit int) ([]*models.User, int64, error) {
 	var users []*models.User
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := r.inOrganization(organizationID).Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
 
@@ -137,7 +146,7 @@ func (r *userRepository) GetAll(page, limit int) ([]*models.User, int64, error)
 	offset := (page - 1) * limit
 
 	// Get paginated results
-	if err := r.db.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := r.inOrganization(organizationID).Select("users.*").Offset(offset).Limit(limit).Order("users.created_at DESC").Find(&users).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to get users: %w", err)
 	}
 
@@ -171,8 +180,12 @@ func (r *userRepository) UpdateFields(id uint, fields map[string]interface{}) er
 
 // UpdateStatus อัพเดทสถานะ active/inactive
 func (r *userRepository) UpdateStatus(id uint, isActive bool) error {
-	if err := r.db.Model(&models.User{}).Where("id = ?", id).Update("is_active", isActive).Error; err != nil {
-		return fmt.Errorf("failed to update user status: %w", err)
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("is_active", isActive)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id %d", ErrUserNotFound, id)
 	}
 	return nil
 }
@@ -185,6 +198,62 @@ func (r *userRepository) UpdateAvatar(id uint, avatarURL string) error {
 	return nil
 }
 
+// RecordFailedLogin เพิ่มจำนวน login ผิดของ user แบบ atomic คืนจำนวนล่าสุด
+// ถ้าครั้งล่าสุดเก่ากว่า window จะเริ่มนับใหม่
+func (r *userRepository) RecordFailedLogin(id uint, now time.Time, window time.Duration) (int, error) {
+	user := &models.User{}
+	result := r.db.Model(user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("CASE WHEN last_failed_login_at IS NULL OR last_failed_login_at < ? THEN 1 ELSE failed_login_attempts + 1 END", now.Add(-window)),
+			"last_failed_login_at":  now,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to record failed login: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, fmt.Errorf("%w: id %d", ErrUserNotFound, id)
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// LockUntil ล็อค user ไม่ให้ login ด้วย password จนถึงเวลาที่กำหนด
+func (r *userRepository) LockUntil(id uint, until time.Time) error {
+	if err := r.db.Model(&models.User{}).Where("id = ?", id).Update("locked_until", until).Error; err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
+
+// ResetFailedLogins ล้างจำนวน login ผิดและปลดล็อค user
+func (r *userRepository) ResetFailedLogins(id uint) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id %d", ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// RecordTOTPStep บันทึก time step ของ TOTP code ที่ใช้แล้วแบบ atomic
+// คืน false ถ้า step นี้หรือหลังจากนี้ถูกใช้ไปแล้ว (กัน replay)
+func (r *userRepository) RecordTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_used_step < ?", id, step).
+		Update("totp_last_used_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record TOTP step: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // Delete soft delete user
 func (r *userRepository) Delete(id uint) error {
 	if err := r.db.Delete(&models.User{}, id).Error; err != nil {
@@ -209,13 +278,13 @@ func (r *userRepository) Restore(id uint) error {
 	return nil
 }
 
-// Search ค้นหา users ด้วย keyword
-func (r *userRepository) Search(keyword string, page, limit int) ([]*models.User, int64, error) {
+// Search ค้นหา users ที่เป็นสมาชิกของ organization ด้วย keyword
+func (r *userRepository) Search(organizationID uint, keyword string, page, limit int) ([]*models.User, int64, error) {
 	var users []*models.User
 	var total int64
 
-	query := r.db.Model(&models.User{}).Where(
-		"name ILIKE ? OR email ILIKE ?",
+	query := r.inOrganization(organizationID).Where(
+		"users.name ILIKE ? OR users.email ILIKE ?",
 		"%"+keyword+"%",
 		"%"+keyword+"%",
 	)
@@ -227,7 +296,7 @@ func (r *userRepository) Search(keyword string, page, limit int) ([]*models.User
 
 	// Get results with pagination
 	offset := (page - 1) * limit
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := query.Select("users.*").Offset(offset).Limit(limit).Order("users.created_at DESC").Find(&users).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to search users: %w", err)
 	}
 
@@ -252,19 +321,19 @@ func (r *userRepository) ExistsByEmail(email string) (bool, error) {
 	return count > 0, nil
 }
 
-// Count นับจำนวน users ทั้งหมด
-func (r *userRepository) Count() (int64, error) {
+// Count นับจำนวนสมาชิกของ organization
+func (r *userRepository) Count(organizationID uint) (int64, error) {
 	var count int64
-	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil {
+	if err := r.inOrganization(organizationID).Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
 	return count, nil
 }
 
-// CountActive นับจำนวน active users
-func (r *userRepository) CountActive() (int64, error) {
+// CountActive นับจำนวนสมาชิกของ organization ที่ active
+func (r *userRepository) CountActive(organizationID uint) (int64, error) {
 	var count int64
-	if err := r.db.Model(&models.User{}).Where("is_active = ?", true).Count(&count).Error; err != nil {
+	if err := r.inOrganization(organizationID).Where("users.is_active = ?", true).Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("failed to count active users: %w", err)
 	}
 	return count, nil
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUserTokenNotFound ไม่พบ token ที่ใช้ได้ (ไม่มี หมดอายุ หรือถูกใช้ไปแล้ว)
+var ErrUserTokenNotFound = errors.New("user token not found")
+
+// UserTokenRepository interface สำหรับ token แบบใช้ครั้งเดียว เช่น ยืนยัน email
+type UserTokenRepository interface {
+	Create(token *models.UserToken) error
+	Consume(purpose, tokenHash string) (*models.UserToken, error)
+	InvalidateForUser(userID uint, purpose string) error
+}
+
+// userTokenRepository struct implements UserTokenRepository interface
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewUserTokenRepository creates new user token repository instance
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก token ใหม่
+func (r *userTokenRepository) Create(token *models.UserToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	return nil
+}
+
+// Consume ใช้ token แบบ atomic: สำเร็จเฉพาะ token ที่ยังไม่หมดอายุและยังไม่ถูกใช้
+func (r *userTokenRepository) Consume(purpose, tokenHash string) (*models.UserToken, error) {
+	now := time.Now()
+	var tokens []*models.UserToken
+	result := r.db.Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, tokenHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to consume user token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 || len(tokens) == 0 {
+		return nil, ErrUserTokenNotFound
+	}
+	return tokens[0], nil
+}
+
+// InvalidateForUser ทำให้ token ที่ยังไม่ถูกใช้ของ user สำหรับ purpose นี้ใช้ไม่ได้
+func (r *userTokenRepository) InvalidateForUser(userID uint, purpose string) error {
+	if err := r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrWebAuthnCredentialNotFound ไม่พบ passkey
+var ErrWebAuthnCredentialNotFound = errors.New("passkey not found")
+
+// WebAuthnCredentialRepository interface สำหรับ passkeys ของ user
+type WebAuthnCredentialRepository interface {
+	Create(credential *models.WebAuthnCredential) error
+	GetByUserID(userID uint) ([]*models.WebAuthnCredential, error)
+	GetByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error)
+	RecordUse(id uint, signCount uint32, backupState bool) (bool, error)
+	MarkCloneWarning(id uint) error
+	Delete(userID, id uint) error
+}
+
+// webAuthnCredentialRepository struct implements WebAuthnCredentialRepository interface
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCredentialRepository creates new passkey repository instance
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{
+		db: db,
+	}
+}
+
+// Create บันทึก passkey ใหม่
+func (r *webAuthnCredentialRepository) Create(credential *models.WebAuthnCredential) error {
+	if err := r.db.Create(credential).Error; err != nil {
+		return fmt.Errorf("failed to create passkey: %w", err)
+	}
+	return nil
+}
+
+// GetByUserID ดึง passkeys ทั้งหมดของ user เรียงตามวันที่ลงทะเบียน
+func (r *webAuthnCredentialRepository) GetByUserID(userID uint) ([]*models.WebAuthnCredential, error) {
+	var credentials []*models.WebAuthnCredential
+	if err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to get passkeys: %w", err)
+	}
+	return credentials, nil
+}
+
+// GetByCredentialID ดึง passkey จาก credential ID ที่ authenticator ส่งมา
+func (r *webAuthnCredentialRepository) GetByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	if err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebAuthnCredentialNotFound
+		}
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+	return &credential, nil
+}
+
+// RecordUse บันทึก sign counter ใหม่แบบ atomic คืน false ถ้า counter ไม่ได้เพิ่มขึ้นจากค่าใน DB
+// (login พร้อมกันจาก authenticator ที่ถูก clone) authenticator ที่ไม่นับ counter ส่ง 0 เสมอ
+func (r *webAuthnCredentialRepository) RecordUse(id uint, signCount uint32, backupState bool) (bool, error) {
+	result := r.db.Model(&models.WebAuthnCredential{}).
+		Where("id = ? AND clone_warning = ? AND (sign_count < ? OR (sign_count = 0 AND ? = 0))", id, false, signCount, signCount).
+		Updates(map[string]interface{}{
+			"sign_count":   signCount,
+			"backup_state": backupState,
+			"last_used_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update passkey: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkCloneWarning ระงับ passkey ที่สงสัยว่าถูก clone
+func (r *webAuthnCredentialRepository) MarkCloneWarning(id uint) error {
+	if err := r.db.Model(&models.WebAuthnCredential{}).Where("id = ?", id).Update("clone_warning", true).Error; err != nil {
+		return fmt.Errorf("failed to flag passkey: %w", err)
+	}
+	return nil
+}
+
+// Delete ลบ passkey ของ user (ลบของคนอื่นไม่ได้)
+func (r *webAuthnCredentialRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete passkey: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+	return nil
+}
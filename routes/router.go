@@ -3,30 +3,169 @@ package routes
 import (
 	controller "collp-backend/controllers"
 	"collp-backend/middleware"
+	"collp-backend/models"
+	"collp-backend/ratelimit"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine) {
+func SetupRoutes(r *gin.Engine, limiter *ratelimit.Limiter, limits ratelimit.Config) {
+	// endpoints ที่รับ credentials จำกัดต่อ IP เข้มกว่า global limit
+	authLimit := limiter.Middleware("auth", limits.Auth, ratelimit.ByIP)
+	// route ที่กำหนด limit ใน RATE_LIMIT_ROUTES ใช้ bucket ของตัวเองแทน bucket auth
+	authRoute := func(name string) gin.HandlerFunc {
+		return limiter.Route(limits, name, ratelimit.ByIP, authLimit)
+	}
+	// กลุ่ม route ที่กำหนด limit ใน RATE_LIMIT_ROUTES จำกัดต่อ user เพิ่มจาก RATE_LIMIT_USER
+	groupLimit := func(name string) gin.HandlerFunc {
+		return limiter.Route(limits, name, ratelimit.ByUser, nil)
+	}
+
+	// Public keys สำหรับตรวจสอบ JWT
+	r.GET("/.well-known/jwks.json", gin.WrapF(controller.JWKS))
+
+	// OAuth2 token endpoint สำหรับ service-to-service (client_credentials grant)
+	r.POST("/oauth/token", authRoute("oauth_token"), gin.WrapF(controller.OAuthToken))
+
 	// Public routes
 	public := r.Group("/api")
 	{
-		// Google OAuth routes
-		public.GET("/auth/google/login", gin.WrapF(controller.GoogleLogin))
-		public.GET("/auth/google/callback", gin.WrapF(controller.GoogleCallback))
+		// OpenID Connect routes (providers จาก OIDC_PROVIDERS_FILE เช่น google, keycloak)
+		public.GET("/auth/providers", gin.WrapF(controller.GetOAuthProviders))
+		public.GET("/auth/:provider/login", authRoute("oauth_login"), handle(controller.OAuthLogin))
+		public.GET("/auth/:provider/callback", authRoute("oauth_callback"), handle(controller.OAuthCallback))
+		public.POST("/auth/exchange", authRoute("exchange"), gin.WrapF(controller.ExchangeAuthCode))
+		public.POST("/auth/refresh", authRoute("refresh"), gin.WrapF(controller.RefreshToken))
+		public.POST("/auth/logout", gin.WrapF(controller.Logout))
+		public.POST("/auth/logout-all", gin.WrapF(controller.LogoutAll))
+		public.POST("/auth/verify-email", authRoute("verify_email"), gin.WrapF(controller.VerifyEmail))
+		public.POST("/auth/password/forgot", authRoute("password_forgot"), gin.WrapF(controller.ForgotPassword))
+		public.POST("/auth/password/reset", authRoute("password_reset"), gin.WrapF(controller.ResetPassword))
+
+		// Two-factor authentication หลัง login ได้ mfa_token
+		public.POST("/auth/mfa/verify", authRoute("mfa_verify"), gin.WrapF(controller.VerifyMFA))
+		public.POST("/auth/mfa/enroll", authRoute("mfa_enroll"), gin.WrapF(controller.BeginMFAEnrollmentChallenge))
+		public.POST("/auth/mfa/enroll/confirm", authRoute("mfa_enroll"), gin.WrapF(controller.ConfirmMFAEnrollmentChallenge))
+
+		// Passkey login (WebAuthn)
+		public.POST("/auth/passkey/login/begin", authRoute("passkey_login"), gin.WrapF(controller.BeginPasskeyLogin))
+		public.POST("/auth/passkey/login/finish", authRoute("passkey_login"), gin.WrapF(controller.FinishPasskeyLogin))
 
 		// CollP auth routes
-		public.POST("/collp/login", gin.WrapF(controller.CollPLogin))
-		public.POST("/collp/register", gin.WrapF(controller.CollPRegister))
+		public.POST("/collp/login", authRoute("login"), gin.WrapF(controller.CollPLogin))
+		public.POST("/collp/register", authRoute("register"), gin.WrapF(controller.CollPRegister))
+
+		// ลิงก์คำเชิญเข้า organization (ตอบรับด้วย invitation_token ตอน login/สมัคร หรือ ?invitation= ของ OAuth)
+		public.POST("/invitations/lookup", authRoute("invitation_lookup"), gin.WrapF(controller.LookupInvitation))
+	}
+
+	userLimit := limiter.Middleware("user", limits.User, ratelimit.ByUser)
+
+	// Authenticated routes ที่ใช้ได้แม้ยังไม่ยืนยัน email
+	authenticated := r.Group("/api")
+	authenticated.Use(middleware.AuthMiddleware(), userLimit)
+	{
+		authenticated.POST("/auth/verify-email/resend", handle(controller.ResendVerificationEmail))
+		authenticated.POST("/auth/impersonation/stop", handle(controller.StopImpersonation))
 	}
 
-	// Private routes (with authentication)
+	// Private routes (with authentication, EMAIL_VERIFICATION_POLICY=required ต้องยืนยัน email ก่อน)
 	private := r.Group("/api")
-	private.Use(gin.WrapH(middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// This is a placeholder handler, the actual handler will be called by Gin
-	}))))
+	private.Use(middleware.AuthMiddleware(), userLimit, middleware.RequireVerifiedEmail())
 	{
+		// endpoints ที่ลบหรือเปลี่ยนข้อมูลสำคัญใช้ไม่ได้ระหว่าง admin impersonate user
+		noImpersonation := middleware.RejectImpersonation()
+
 		private.GET("/collp/main-menu", gin.WrapF(controller.MainMenu))
+
+		// User management routes
+		users := private.Group("/users")
+		users.Use(groupLimit("users"))
+		{
+			users.GET("", middleware.RequirePermission(models.PermUsersRead), handle(controller.GetAllUsers))
+			users.POST("", middleware.RequirePermission(models.PermUsersCreate), handle(controller.CreateUser))
+			users.GET("/search", middleware.RequirePermission(models.PermUsersRead), handle(controller.SearchUsers))
+			users.GET("/stats", middleware.RequirePermission(models.PermUsersStats), handle(controller.GetUserStats))
+			users.GET("/:id", middleware.RequirePermission(models.PermUsersRead), handle(controller.GetUserByID))
+			// เจ้าของ profile แก้ไขของตัวเองได้ ส่วนของคนอื่นต้องมี users:update (ตรวจใน controller)
+			users.PUT("/:id", noImpersonation, handle(controller.UpdateUserProfile))
+			users.PUT("/:id/role", noImpersonation, middleware.RequirePermission(models.PermRolesAssign), handle(controller.AssignUserRole))
+			users.DELETE("/:id", noImpersonation, middleware.RequirePermission(models.PermUsersDelete), handle(controller.DeleteUser))
+			users.DELETE("/:id/permanent", noImpersonation, middleware.RequirePermission(models.PermUsersPurge), handle(controller.HardDeleteUser))
+			users.PATCH("/:id/deactivate", noImpersonation, middleware.RequirePermission(models.PermUsersDeactivate), handle(controller.DeactivateUser))
+			users.PATCH("/:id/activate", middleware.RequirePermission(models.PermUsersDeactivate), handle(controller.ActivateUser))
+			users.PATCH("/:id/unlock", middleware.RequirePermission(models.PermUsersUnlock), handle(controller.UnlockUser))
+			users.GET("/:id/audit-logs", middleware.RequirePermission(models.PermAuditRead), handle(controller.GetUserAuditLogs))
+			users.GET("/:id/sessions", middleware.RequirePermission(models.PermAuditRead), handle(controller.GetUserSessions))
+			users.DELETE("/:id/sessions/:session_id", noImpersonation, middleware.RequirePermission(models.PermUsersDeactivate), handle(controller.DeleteUserSession))
+			// admin ดูระบบในฐานะ user (token อายุสั้นที่มี claim act)
+			users.POST("/:id/impersonate", middleware.RequireSessionToken(), noImpersonation, middleware.RequirePermission(models.PermUsersImpersonate), handle(controller.StartImpersonation))
+		}
+		private.GET("/roles", middleware.RequirePermission(models.PermRolesAssign), handle(controller.GetRoles))
+		private.PUT("/roles/:name/mfa", noImpersonation, middleware.RequirePermission(models.PermRolesAssign), handle(controller.SetRoleMFARequirement))
+
+		// Organizations (tenants) สิทธิ์จัดการสมาชิกมาจาก role ใน organization (ตรวจใน service)
+		// ใช้ได้เฉพาะ user ที่ login จริง เพราะ personal access token ไม่มี scope ของ organization
+		private.POST("/auth/switch-organization", middleware.RequireSessionToken(), noImpersonation, handle(controller.SwitchOrganization))
+		organizations := private.Group("/organizations")
+		organizations.Use(middleware.RequireSessionToken(), groupLimit("organizations"))
+		{
+			organizations.POST("", noImpersonation, middleware.RequirePermission(models.PermOrgsCreate), handle(controller.CreateOrganization))
+			organizations.GET("/:id/members", handle(controller.GetOrganizationMembers))
+			organizations.POST("/:id/members", noImpersonation, handle(controller.AddOrganizationMember))
+			organizations.PUT("/:id/members/:user_id", noImpersonation, handle(controller.UpdateOrganizationMemberRole))
+			organizations.DELETE("/:id/members/:user_id", noImpersonation, handle(controller.RemoveOrganizationMember))
+			organizations.GET("/:id/invitations", handle(controller.GetOrganizationInvitations))
+			organizations.POST("/:id/invitations", noImpersonation, handle(controller.CreateOrganizationInvitation))
+			organizations.POST("/:id/invitations/:invitation_id/resend", noImpersonation, handle(controller.ResendOrganizationInvitation))
+			organizations.DELETE("/:id/invitations/:invitation_id", noImpersonation, handle(controller.RevokeOrganizationInvitation))
+		}
+		private.POST("/invitations/accept", middleware.RequireSessionToken(), noImpersonation, gin.WrapF(controller.AcceptInvitation))
+
+		// OAuth clients สำหรับ service-to-service (จัดการได้เฉพาะ user ที่ login จริง)
+		clients := private.Group("/oauth-clients")
+		clients.Use(middleware.RequireSessionToken(), groupLimit("oauth_clients"), noImpersonation, middleware.RequirePermission(models.PermClientsManage))
+		{
+			clients.GET("", handle(controller.GetOAuthClients))
+			clients.POST("", handle(controller.CreateOAuthClient))
+			clients.POST("/:id/secret", handle(controller.RotateOAuthClientSecret))
+			clients.DELETE("/:id", handle(controller.DeleteOAuthClient))
+		}
+
+		// Two-factor authentication, passkeys, บัญชี provider ที่ผูกและ personal access tokens ของ user ปัจจุบัน
+		// ใช้ personal access token จัดการไม่ได้ ต้อง login จริง และแก้ไขไม่ได้ระหว่าง impersonate
+		me := private.Group("/me")
+		me.Use(middleware.RequireSessionToken(), groupLimit("me"))
+		{
+			me.GET("/mfa", handle(controller.GetMFAStatus))
+			me.POST("/mfa/totp", noImpersonation, handle(controller.BeginTOTPEnrollment))
+			me.POST("/mfa/totp/confirm", noImpersonation, handle(controller.ConfirmTOTPEnrollment))
+			me.DELETE("/mfa/totp", noImpersonation, handle(controller.DisableTOTP))
+			me.POST("/mfa/recovery-codes", noImpersonation, handle(controller.RegenerateRecoveryCodes))
+			me.GET("/passkeys", handle(controller.GetPasskeys))
+			me.POST("/passkeys/register/begin", noImpersonation, handle(controller.BeginPasskeyRegistration))
+			me.POST("/passkeys/register/finish", noImpersonation, handle(controller.FinishPasskeyRegistration))
+			me.DELETE("/passkeys/:id", noImpersonation, handle(controller.DeletePasskey))
+			me.GET("/identities", handle(controller.GetIdentities))
+			me.POST("/identities/:provider/link", noImpersonation, handle(controller.LinkIdentity))
+			me.DELETE("/identities/:id", noImpersonation, handle(controller.UnlinkIdentity))
+			me.GET("/tokens", handle(controller.GetPersonalAccessTokens))
+			me.POST("/tokens", noImpersonation, handle(controller.CreatePersonalAccessToken))
+			me.DELETE("/tokens/:id", noImpersonation, handle(controller.RevokePersonalAccessToken))
+			me.GET("/sessions", handle(controller.GetSessions))
+			me.DELETE("/sessions/:id", noImpersonation, handle(controller.DeleteSession))
+			me.GET("/organizations", handle(controller.GetMyOrganizations))
+		}
+	}
+}
+
+// handle ครอบ net/http handler และส่ง path params ของ gin ให้อ่านผ่าน r.PathValue
+func handle(handler http.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, param := range c.Params {
+			c.Request.SetPathValue(param.Key, param.Value)
+		}
+		handler(c.Writer, c.Request)
 	}
 }
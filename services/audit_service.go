@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+)
+
+// AuditService interface สำหรับบันทึกเหตุการณ์ด้าน security
+type AuditService interface {
+	Record(entry *models.AuditLog)
+	GetUserAuditLogs(userID uint, page, limit int) ([]*models.AuditLog, int64, error)
+}
+
+// auditService struct implements AuditService interface
+type auditService struct {
+	auditRepo repositories.AuditLogRepository
+}
+
+// NewAuditService creates new audit service instance
+func NewAuditService(auditRepo repositories.AuditLogRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// Record บันทึก audit log ถ้าบันทึกไม่สำเร็จจะ log ไว้แต่ไม่ทำให้ request ล้มเหลว
+func (s *auditService) Record(entry *models.AuditLog) {
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Failed to write audit log %s: %v", entry.Action, err)
+	}
+}
+
+// GetUserAuditLogs ดึง audit log ของ user แบบ pagination
+func (s *auditService) GetUserAuditLogs(userID uint, page, limit int) ([]*models.AuditLog, int64, error) {
+	if userID == 0 {
+		return nil, 0, fmt.Errorf("%w: invalid user id", ErrInvalidInput)
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	entries, total, err := s.auditRepo.GetByUserID(userID, page, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+	return entries, total, nil
+}
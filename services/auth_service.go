@@ -2,145 +2,323 @@ package services
 
 import (
 	"context"
-	"crypto/rsa"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
-	"os"
 	"time"
 
+	"collp-backend/models"
+	"collp-backend/oidc"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+	"collp-backend/validators"
+
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+)
+
+var (
+	// ErrInvalidCredentials email หรือ password ไม่ถูกต้อง
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUserInactive บัญชีถูกปิดการใช้งาน
+	ErrUserInactive = errors.New("user account is inactive")
+	// ErrInvalidOAuthState state ไม่ถูกต้อง หมดอายุ หรือถูกใช้ไปแล้ว (หรือเป็นของ provider อื่น)
+	ErrInvalidOAuthState = errors.New("invalid, expired or already used OAuth state")
+	// ErrInvalidAuthCode authorization code ไม่ถูกต้อง หมดอายุ หรือถูกใช้ไปแล้ว
+	ErrInvalidAuthCode = errors.New("invalid, expired or already used authorization code")
+	// ErrInvalidRefreshToken refresh token ไม่ถูกต้อง หมดอายุ หรือถูกเพิกถอน
+	ErrInvalidRefreshToken = errors.New("invalid, expired or revoked refresh token")
+	// ErrInvalidAccessToken access token ไม่ถูกต้องหรือหมดอายุ
+	ErrInvalidAccessToken = errors.New("invalid or expired access token")
+	// ErrRefreshTokenReuse refresh token ที่ใช้ไปแล้วถูกส่งมาอีก (สัญญาณว่า token ถูกขโมย)
+	ErrRefreshTokenReuse = errors.New("refresh token reuse detected")
+	// ErrIdentityProviderUnavailable ติดต่อ OIDC provider ไม่ได้
+	ErrIdentityProviderUnavailable = errors.New("identity provider is unavailable")
+)
+
+const (
+	// OAuthStateTTL อายุของ state ระหว่าง redirect ไป provider จนถึง callback
+	OAuthStateTTL = 10 * time.Minute
+	// AuthCodeTTL อายุของ authorization code ที่ส่งให้ frontend แลกเป็น JWT
+	AuthCodeTTL = time.Minute
+	// RefreshTokenTTL อายุของ refresh token แต่ละตัว
+	RefreshTokenTTL = 30 * 24 * time.Hour
 )
 
 type AuthService struct {
-	googleOauthConfig *oauth2.Config
-	privateKey        *rsa.PrivateKey
+	providers   *oidc.Registry
+	keys        *utils.KeyManager
+	userRepo    repositories.UserRepository
+	userService UserService
+	refreshRepo repositories.RefreshTokenRepository
+	sessionRepo repositories.SessionRepository
+	memberships repositories.MembershipRepository
+	revocations TokenRevocationService
+	throttle    LoginThrottleService
+	mfa         MFAService
+	passkeys    WebAuthnService
+	identities  IdentityService
+	invitations InvitationService
+	// oauthLogins จับคู่ state กับ provider, PKCE code_verifier และ nonce ของแต่ละ login
+	oauthLogins *oneTimeStore[*oauthLogin]
+	// authCodes เก็บผล login ที่รอ frontend มาแลกด้วย authorization code
+	authCodes *oneTimeStore[*AuthResult]
+	// mfaChallenges login ที่รอยืนยันปัจจัยที่สองด้วย mfa_token
+	mfaChallenges *oneTimeStore[*mfaChallenge]
 }
 
-type GoogleUserInfo struct {
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
-	VerifiedEmail bool   `json:"verified_email"`
-	Token         string `json:"token"`
-	TokenExpiry   int64  `json:"token_expiry"`
+// AuthResult ผลลัพธ์การเข้าสู่ระบบที่ส่งกลับให้ client
+// ถ้า MFARequired หรือ MFAEnrollmentRequired จะมีแค่ MFAToken สำหรับยืนยันปัจจัยที่สอง
+type AuthResult struct {
+	User               *models.User `json:"user,omitempty"`
+	Token              string       `json:"token,omitempty"`
+	TokenExpiry        int64        `json:"token_expiry,omitempty"`
+	RefreshToken       string       `json:"refresh_token,omitempty"`
+	RefreshTokenExpiry int64        `json:"refresh_token_expiry,omitempty"`
+
+	MFARequired           bool     `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool     `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string   `json:"mfa_token,omitempty"`
+	RecoveryCodes         []string `json:"recovery_codes,omitempty"`
+
+	// InvitationError เหตุผลที่ตอบรับคำเชิญที่มากับการ login ไม่ได้ (login ยังสำเร็จ)
+	InvitationError string `json:"invitation_error,omitempty"`
+
+	// LinkedProvider ตั้งค่าเมื่อ callback เป็นการผูก provider กับ user ที่ login อยู่ (ไม่ได้ออก token ใหม่)
+	LinkedProvider string `json:"linked_provider,omitempty"`
+}
+
+// IsMFAChallenge ตรวจสอบว่าผลลัพธ์ยังต้องยืนยันปัจจัยที่สองก่อนได้ JWT
+func (r *AuthResult) IsMFAChallenge() bool {
+	return r.MFAToken != ""
+}
+
+// oauthLogin สถานะของ login ที่ redirect ไป OIDC provider แล้วรอ callback
+type oauthLogin struct {
+	provider string
+	verifier string
+	nonce    string
+	// linkUserID user ที่ขอผูก provider (0 = login ปกติ)
+	linkUserID uint
+	// invitation token คำเชิญที่ตอบรับหลัง login สำเร็จ (ว่าง = ไม่มีคำเชิญ)
+	invitation string
+}
+
+// OAuthProviderInfo provider ที่แสดงให้ frontend เลือก login
+type OAuthProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
 }
 
 type AuthServiceInterface interface {
-	InitGoogleOauth() error
-	GetGoogleAuthURL(state string) string
-	HandleGoogleCallback(code, state string) (*GoogleUserInfo, error)
+	GetOAuthProviders() []OAuthProviderInfo
+	GetOAuthLoginURL(ctx context.Context, provider, state, invitation string) (string, error)
+	GetOAuthLinkURL(ctx context.Context, provider, state string, userID uint) (string, error)
+	HandleOAuthCallback(ctx context.Context, provider, code, state string, client ClientInfo) (*AuthResult, error)
+	CreateAuthCode(result *AuthResult) string
+	ExchangeAuthCode(code string) (*AuthResult, error)
+	Login(req validators.UserLoginRequest, client ClientInfo) (*AuthResult, error)
+	Register(req validators.UserRegistrationRequest, client ClientInfo) (*AuthResult, error)
+	RefreshTokens(refreshToken string, client ClientInfo) (*AuthResult, error)
+	Logout(accessToken, refreshToken string) error
+	LogoutAll(accessToken string) error
+	VerifyMFA(mfaToken, code string, client ClientInfo) (*AuthResult, error)
+	BeginMFAEnrollment(mfaToken string) (*TOTPEnrollment, error)
+	ConfirmMFAEnrollment(mfaToken, code string, client ClientInfo) (*AuthResult, error)
+	BeginPasskeyLogin() (*PasskeyChallenge, error)
+	LoginWithPasskey(sessionToken string, credential []byte, client ClientInfo) (*AuthResult, error)
+	SwitchOrganization(claims *utils.JWTClaims, organizationID uint) (*AuthResult, error)
+}
+
+func NewAuthService(providers *oidc.Registry, keys *utils.KeyManager, userRepo repositories.UserRepository, userService UserService, refreshRepo repositories.RefreshTokenRepository, sessionRepo repositories.SessionRepository, memberships repositories.MembershipRepository, revocations TokenRevocationService, throttle LoginThrottleService, mfa MFAService, passkeys WebAuthnService, identities IdentityService, invitations InvitationService) AuthServiceInterface {
+	return &AuthService{
+		providers:     providers,
+		keys:          keys,
+		userRepo:      userRepo,
+		userService:   userService,
+		refreshRepo:   refreshRepo,
+		sessionRepo:   sessionRepo,
+		memberships:   memberships,
+		revocations:   revocations,
+		throttle:      throttle,
+		mfa:           mfa,
+		passkeys:      passkeys,
+		identities:    identities,
+		invitations:   invitations,
+		oauthLogins:   newOneTimeStore[*oauthLogin](OAuthStateTTL),
+		authCodes:     newOneTimeStore[*AuthResult](AuthCodeTTL),
+		mfaChallenges: newOneTimeStore[*mfaChallenge](MFAChallengeTTL),
+	}
 }
 
-func NewAuthService() AuthServiceInterface {
-	return &AuthService{}
+// GetOAuthProviders รายการ OIDC providers ที่เปิดใช้
+func (s *AuthService) GetOAuthProviders() []OAuthProviderInfo {
+	providers := s.providers.Providers()
+	infos := make([]OAuthProviderInfo, 0, len(providers))
+	for _, provider := range providers {
+		infos = append(infos, OAuthProviderInfo{Name: provider.Name(), DisplayName: provider.DisplayName()})
+	}
+	return infos
 }
 
-// InitGoogleOauth โหลด Google OAuth config และ RSA Key
-func (s *AuthService) InitGoogleOauth() error {
-	// Setup Google OAuth Config
-	s.googleOauthConfig = &oauth2.Config{
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		Scopes: []string{
-			os.Getenv("GOOGLE_USERINFO_EMAIL"),
-			os.Getenv("GOOGLE_USERINFO_PROFILE"),
-		},
-		Endpoint: google.Endpoint,
+// GetOAuthLoginURL สร้าง URL สำหรับ redirect ไป provider พร้อม PKCE challenge และ nonce
+// invitation (ถ้ามี) ตรวจสอบก่อน redirect และตอบรับหลัง callback
+func (s *AuthService) GetOAuthLoginURL(ctx context.Context, providerName, state, invitation string) (string, error) {
+	if invitation != "" {
+		if _, err := s.invitations.GetInvitation(invitation); err != nil {
+			return "", err
+		}
 	}
+	return s.startOAuth(ctx, providerName, state, 0, invitation)
+}
+
+// GetOAuthLinkURL สร้าง URL สำหรับผูก provider กับ user ที่ login อยู่
+func (s *AuthService) GetOAuthLinkURL(ctx context.Context, providerName, state string, userID uint) (string, error) {
+	return s.startOAuth(ctx, providerName, state, userID, "")
+}
 
-	// โหลด RSA Private Key จากไฟล์
-	keyData, err := ioutil.ReadFile("rsa.pem")
+// startOAuth เก็บ PKCE verifier, nonce และ user ที่ขอผูก (ถ้ามี) ไว้กับ state จนกว่าจะถึง callback
+func (s *AuthService) startOAuth(ctx context.Context, providerName, state string, linkUserID uint, invitation string) (string, error) {
+	provider, err := s.providers.Get(providerName)
 	if err != nil {
-		return fmt.Errorf("failed to read rsa.pem: %v", err)
+		return "", err
 	}
 
-	// แปลง PEM เป็น RSA Private Key
-	s.privateKey, err = jwt.ParseRSAPrivateKeyFromPEM(keyData)
+	login := &oauthLogin{
+		provider:   providerName,
+		verifier:   oauth2.GenerateVerifier(),
+		nonce:      utils.GenerateRandomString(32),
+		linkUserID: linkUserID,
+		invitation: invitation,
+	}
+	authURL, err := provider.AuthCodeURL(ctx, state, login.nonce, login.verifier)
 	if err != nil {
-		return fmt.Errorf("failed to parse RSA private key: %v", err)
+		return "", err
 	}
 
-	return nil
+	s.oauthLogins.Put(state, login)
+	return authURL, nil
 }
 
-// GetGoogleAuthURL สร้าง URL สำหรับ redirect ไป Google
-func (s *AuthService) GetGoogleAuthURL(state string) string {
-	return s.googleOauthConfig.AuthCodeURL(state)
-}
+// HandleOAuthCallback แลก code กับ provider ตรวจสอบ id_token แล้วสร้าง JWT
+func (s *AuthService) HandleOAuthCallback(ctx context.Context, providerName, code, state string, client ClientInfo) (*AuthResult, error) {
+	provider, err := s.providers.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
 
-// HandleGoogleCallback จัดการ callback จาก Google และสร้าง JWT
-func (s *AuthService) HandleGoogleCallback(code, state string) (*GoogleUserInfo, error) {
-	// Validate state
-	if state != "random-state-string" {
-		return nil, errors.New("state parameter doesn't match")
+	// Validate state (ใช้ได้ครั้งเดียว ยังไม่หมดอายุ และต้องเริ่มจาก provider เดียวกัน)
+	login, ok := s.oauthLogins.Take(state)
+	if state == "" || !ok || login.provider != providerName {
+		return nil, ErrInvalidOAuthState
 	}
 
-	// Exchange code for token
-	token, err := s.googleOauthConfig.Exchange(context.Background(), code)
+	identity, err := provider.Exchange(ctx, code, login.verifier, login.nonce)
 	if err != nil {
 		var netErr net.Error
 		if errors.As(err, &netErr) {
-			return nil, fmt.Errorf("network error: %v", err)
+			return nil, fmt.Errorf("%w: %v", ErrIdentityProviderUnavailable, err)
 		}
-		return nil, fmt.Errorf("token exchange failed: %v", err)
+		return nil, err
 	}
 
-	// Get user info from Google
-	userInfo, err := s.fetchGoogleUserInfo(token)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user info: %v", err)
+	// ผูก provider กับ user ที่เริ่ม flow จากหน้าบัญชีของตัวเอง
+	if login.linkUserID != 0 {
+		if _, err := s.identities.LinkIdentity(login.linkUserID, identity); err != nil {
+			return nil, err
+		}
+		return &AuthResult{LinkedProvider: providerName}, nil
 	}
 
-	// Generate JWT token
-	jwtToken, expiry, err := s.generateJWTToken(userInfo.Email)
+	// หา user จาก identity ที่ผูกไว้ หรือสร้าง/ผูกใหม่ด้วย email ที่ provider ยืนยันแล้ว
+	user, err := s.userService.GetOrCreateUser(identity)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate JWT: %v", err)
+		return nil, fmt.Errorf("failed to persist user: %w", err)
 	}
 
-	// Add token to user info
-	userInfo.Token = jwtToken
-	userInfo.TokenExpiry = expiry
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
 
-	return userInfo, nil
+	// Generate JWT token (หรือ mfa_token ถ้าต้องใช้ 2FA) คำเชิญตอบรับหลังผ่าน 2FA แล้ว
+	return s.completeLogin(user, client, login.invitation)
 }
 
-// fetchGoogleUserInfo ดึงข้อมูล user จาก Google API
-func (s *AuthService) fetchGoogleUserInfo(token *oauth2.Token) (*GoogleUserInfo, error) {
-	client := s.googleOauthConfig.Client(context.Background(), token)
-	resp, err := client.Get(os.Getenv("GOOGLE_USERINFO"))
-	if err != nil {
-		return nil, fmt.Errorf("failed getting user info: %v", err)
-	}
-	defer resp.Body.Close()
+// CreateAuthCode สร้าง authorization code แบบใช้ครั้งเดียวสำหรับส่งต่อผล login ให้ frontend
+func (s *AuthService) CreateAuthCode(result *AuthResult) string {
+	code := utils.GenerateRandomString(43)
+	s.authCodes.Put(code, result)
+	return code
+}
 
-	var userInfo GoogleUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %v", err)
+// ExchangeAuthCode แลก authorization code เป็นผล login (JWT)
+func (s *AuthService) ExchangeAuthCode(code string) (*AuthResult, error) {
+	result, ok := s.authCodes.Take(code)
+	if code == "" || !ok {
+		return nil, ErrInvalidAuthCode
 	}
+	return result, nil
+}
 
-	return &userInfo, nil
+// issueToken สร้าง JWT ให้ user ด้วย utils.GenerateJWT พร้อม refresh token ใน family (session) ใหม่
+func (s *AuthService) issueToken(user *models.User, client ClientInfo) (*AuthResult, error) {
+	session := newSession(user.ID, utils.GenerateRandomString(22), client)
+	organizationID, err := defaultOrganizationID(s.memberships, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	session.OrganizationID = organizationID
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	return s.issueTokenForSession(user, session)
 }
 
-// generateJWTToken สร้าง JWT token
-func (s *AuthService) generateJWTToken(email string) (string, int64, error) {
-	expTime := time.Now().Add(2 * time.Hour).Unix()
-	claims := jwt.MapClaims{
-		"email": email,
-		"exp":   expTime,
-		"iat":   time.Now().Unix(),
+// issueTokenForSession สร้าง access token และ refresh token ใหม่ใน family ของ session
+func (s *AuthService) issueTokenForSession(user *models.User, session *models.Session) (*AuthResult, error) {
+	expiry := time.Now().Add(utils.AccessTokenTTL).Unix()
+	permissions, err := s.userService.GetRolePermissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationID, err := s.activeOrganization(user.ID, session)
+	if err != nil {
+		return nil, err
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	tokenString, err := jwtToken.SignedString(s.privateKey)
+	jti := utils.GenerateRandomString(22)
+	token, err := utils.GenerateJWT(utils.JWTClaims{
+		UserID:           user.ID,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		Role:             user.Role,
+		Permissions:      permissions,
+		SessionID:        session.ID,
+		OrgID:            organizationID,
+		RegisteredClaims: jwt.RegisteredClaims{ID: jti},
+	}, s.keys)
 	if err != nil {
-		return "", 0, err
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	refreshToken := utils.GenerateRandomString(64)
+	refreshExpiry := time.Now().Add(RefreshTokenTTL)
+	if err := s.refreshRepo.Create(&models.RefreshToken{
+		UserID:        user.ID,
+		FamilyID:      session.FamilyID,
+		TokenHash:     utils.HashToken(refreshToken),
+		ExpiresAt:     refreshExpiry,
+		AccessTokenID: jti,
+	}); err != nil {
+		return nil, err
 	}
 
-	return tokenString, expTime, nil
+	return &AuthResult{
+		User:               user,
+		Token:              token,
+		TokenExpiry:        expiry,
+		RefreshToken:       refreshToken,
+		RefreshTokenExpiry: refreshExpiry.Unix(),
+	}, nil
 }
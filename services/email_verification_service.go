@@ -0,0 +1,173 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"collp-backend/mailer"
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+var (
+	// ErrInvalidVerificationToken token ยืนยัน email ไม่ถูกต้อง หมดอายุ หรือถูกใช้ไปแล้ว
+	ErrInvalidVerificationToken = errors.New("invalid, expired or already used verification token")
+	// ErrEmailAlreadyVerified email ของ user ยืนยันแล้ว
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+)
+
+// EmailVerificationTokenTTL อายุของลิงก์ยืนยัน email
+const EmailVerificationTokenTTL = 24 * time.Hour
+
+// EmailVerificationService interface สำหรับส่งและตรวจสอบลิงก์ยืนยัน email
+type EmailVerificationService interface {
+	SendVerification(user *models.User) error
+	ResendVerification(userID uint) error
+	VerifyEmail(token string) (*models.User, error)
+}
+
+// emailVerificationService struct implements EmailVerificationService interface
+type emailVerificationService struct {
+	userRepo  repositories.UserRepository
+	tokenRepo repositories.UserTokenRepository
+	mail      mailer.Mailer
+	// secret key ของ HMAC ที่เซ็น user ID, email และเวลาหมดอายุลงในลิงก์
+	secret []byte
+	// verifyURL หน้า frontend ที่รับ ?token= แล้วเรียก POST /api/auth/verify-email
+	verifyURL string
+}
+
+// NewEmailVerificationService creates new email verification service instance
+func NewEmailVerificationService(userRepo repositories.UserRepository, tokenRepo repositories.UserTokenRepository, mail mailer.Mailer, secret []byte, verifyURL string) EmailVerificationService {
+	return &emailVerificationService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mail:      mail,
+		secret:    secret,
+		verifyURL: verifyURL,
+	}
+}
+
+// LoadEmailVerificationSecret อ่าน key ที่ใช้เซ็นลิงก์ยืนยัน email จาก EMAIL_VERIFICATION_SECRET
+// ถ้าไม่ได้ตั้งจะสุ่ม key ใหม่ทุกครั้งที่ start (ลิงก์ที่ส่งไปก่อน restart ใช้ไม่ได้)
+func LoadEmailVerificationSecret() []byte {
+	if secret := os.Getenv("EMAIL_VERIFICATION_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	log.Println("EMAIL_VERIFICATION_SECRET is not set, verification links will not survive a restart")
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
+
+// verificationMessage ข้อมูลที่ลิงก์ยืนยันผูกไว้: เปลี่ยน email แล้วลิงก์เดิมใช้ไม่ได้
+func verificationMessage(userID uint, email string, expiresAt time.Time, nonce string) string {
+	return fmt.Sprintf("%d|%s|%d|%s", userID, strings.ToLower(email), expiresAt.Unix(), nonce)
+}
+
+// SendVerification สร้าง token ใหม่ (token เก่าที่ยังไม่ใช้จะใช้ไม่ได้) แล้วส่งลิงก์ทาง email
+func (s *emailVerificationService) SendVerification(user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := s.tokenRepo.InvalidateForUser(user.ID, models.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	// token คือ nonce สุ่ม 256 bit (ฐานข้อมูลเก็บแค่ hash เพื่อใช้ได้ครั้งเดียว) และ HMAC ของ user ID, email และเวลาหมดอายุ
+	nonce := utils.GenerateRandomString(43)
+	expiresAt := time.Now().Add(EmailVerificationTokenTTL).Truncate(time.Second)
+	if err := s.tokenRepo.Create(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeEmailVerification,
+		TokenHash: utils.HashToken(nonce),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+	token := nonce + "." + utils.SignToken(s.secret, verificationMessage(user.ID, user.Email, expiresAt, nonce))
+
+	link, err := withTokenParam(s.verifyURL, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mail.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your CollP email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours. If you did not create a CollP account, you can ignore this email.\n",
+			user.Name, link, int(EmailVerificationTokenTTL.Hours())),
+	}); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// ResendVerification ส่งลิงก์ยืนยัน email ให้ user ที่ login อยู่อีกครั้ง
+func (s *emailVerificationService) ResendVerification(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.SendVerification(user)
+}
+
+// VerifyEmail ใช้ token ยืนยัน email (ใช้ได้ครั้งเดียว และเฉพาะกับ email ที่ส่งลิงก์ไป)
+func (s *emailVerificationService) VerifyEmail(token string) (*models.User, error) {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" || signature == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	stored, err := s.tokenRepo.Consume(models.TokenPurposeEmailVerification, utils.HashToken(nonce))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserTokenNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// ลิงก์ที่ส่งไปยัง email เดิมก่อนเปลี่ยน email ยืนยัน email ใหม่ไม่ได้
+	if !utils.VerifyTokenSignature(s.secret, verificationMessage(user.ID, user.Email, stored.ExpiresAt, nonce), signature) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.userRepo.UpdateFields(user.ID, map[string]interface{}{"email_verified_at": now}); err != nil {
+			return nil, fmt.Errorf("failed to mark email as verified: %w", err)
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	return user, nil
+}
+
+// withTokenParam เพิ่ม ?token= ต่อท้าย URL ของ frontend
+func withTokenParam(baseURL, token string) (string, error) {
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid link base URL %q: %w", baseURL, err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"collp-backend/models"
+)
+
+func TestVerifyEmailIsBoundToEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		// modify เปลี่ยน token หรือ user ระหว่างส่งลิงก์กับกดลิงก์
+		modify  func(token string, user *models.User) string
+		wantErr error
+	}{
+		{
+			name:   "valid link",
+			modify: func(token string, user *models.User) string { return token },
+		},
+		{
+			name: "email changed after the link was sent",
+			modify: func(token string, user *models.User) string {
+				user.Email = "other@example.com"
+				return token
+			},
+			wantErr: ErrInvalidVerificationToken,
+		},
+		{
+			name:    "tampered signature",
+			modify:  func(token string, user *models.User) string { return token + "x" },
+			wantErr: ErrInvalidVerificationToken,
+		},
+		{
+			name:    "missing signature",
+			modify:  func(token string, user *models.User) string { return token[:43] },
+			wantErr: ErrInvalidVerificationToken,
+		},
+	}
+
+	for _, tt := range tests {
+		users := &fakeResetUsers{fakePasskeyUsers: fakePasskeyUsers{users: map[uint]*models.User{
+			7: {ID: 7, Email: "user@example.com", IsActive: true},
+		}}}
+		mail := &fakeMailer{}
+		service := NewEmailVerificationService(users, &fakeUserTokens{}, mail, []byte("secret"), "https://app.example.com/verify")
+
+		if err := service.SendVerification(users.users[7]); err != nil {
+			t.Fatalf("%s: SendVerification error: %v", tt.name, err)
+		}
+		if len(mail.sent) != 1 {
+			t.Fatalf("%s: sent %d emails, want 1", tt.name, len(mail.sent))
+		}
+		link, err := url.Parse(findLink(mail.sent[0].Body))
+		if err != nil {
+			t.Fatalf("%s: invalid link: %v", tt.name, err)
+		}
+
+		token := tt.modify(link.Query().Get("token"), users.users[7])
+		user, err := service.VerifyEmail(token)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if users.updated != nil {
+				t.Errorf("%s: email marked as verified", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: VerifyEmail error: %v", tt.name, err)
+		}
+		if user.EmailVerifiedAt == nil || users.updated["email_verified_at"] == nil {
+			t.Errorf("%s: email not marked as verified", tt.name)
+		}
+
+		// ลิงก์ใช้ได้ครั้งเดียว
+		if _, err := service.VerifyEmail(token); !errors.Is(err, ErrInvalidVerificationToken) {
+			t.Errorf("%s: reused link: error = %v, want %v", tt.name, err, ErrInvalidVerificationToken)
+		}
+	}
+}
+
+// findLink หา URL แรกในเนื้อหา email
+func findLink(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"collp-backend/models"
+	"collp-backend/oidc"
+	"collp-backend/repositories"
+)
+
+var (
+	// ErrIdentityAlreadyLinked บัญชีของ provider นี้ผูกกับ user อื่นอยู่แล้ว
+	ErrIdentityAlreadyLinked = errors.New("this provider account is already linked to another user")
+	// ErrIdentityLinkRequired มี user ที่ใช้ email นี้แล้วแต่ provider ไม่ได้ยืนยัน email จึงผูกอัตโนมัติไม่ได้
+	ErrIdentityLinkRequired = errors.New("an account with this email already exists, sign in and link this provider from your account")
+	// ErrLastLoginMethod ห้ามลบวิธี login สุดท้ายของ user
+	ErrLastLoginMethod = errors.New("cannot remove the last sign-in method")
+)
+
+// IdentityService interface สำหรับผูก/ยกเลิกบัญชี OIDC provider ของ user
+type IdentityService interface {
+	ListIdentities(userID uint) ([]*models.UserIdentity, error)
+	LinkIdentity(userID uint, identity *oidc.Identity) (*models.UserIdentity, error)
+	UnlinkIdentity(userID, id uint) error
+}
+
+// identityService struct implements IdentityService interface
+type identityService struct {
+	userRepo       repositories.UserRepository
+	identityRepo   repositories.UserIdentityRepository
+	credentialRepo repositories.WebAuthnCredentialRepository
+	audit          AuditService
+}
+
+// NewIdentityService creates new identity service instance
+func NewIdentityService(userRepo repositories.UserRepository, identityRepo repositories.UserIdentityRepository, credentialRepo repositories.WebAuthnCredentialRepository, audit AuditService) IdentityService {
+	return &identityService{
+		userRepo:       userRepo,
+		identityRepo:   identityRepo,
+		credentialRepo: credentialRepo,
+		audit:          audit,
+	}
+}
+
+// countLoginMethods นับวิธี login ที่ user ยังใช้ได้: password, passkeys และ identities ที่ผูกไว้
+func countLoginMethods(user *models.User, identityRepo repositories.UserIdentityRepository, credentialRepo repositories.WebAuthnCredentialRepository) (int, error) {
+	count := 0
+	if user.PasswordHash != "" {
+		count++
+	}
+
+	identities, err := identityRepo.CountByUserID(user.ID)
+	if err != nil {
+		return 0, err
+	}
+	credentials, err := credentialRepo.GetByUserID(user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return count + int(identities) + len(credentials), nil
+}
+
+// ListIdentities ดึง providers ที่ user ผูกไว้
+func (s *identityService) ListIdentities(userID uint) ([]*models.UserIdentity, error) {
+	return s.identityRepo.GetByUserID(userID)
+}
+
+// LinkIdentity ผูกบัญชี provider กับ user ที่ login อยู่ (ผูกซ้ำกับ user เดิมได้)
+func (s *identityService) LinkIdentity(userID uint, identity *oidc.Identity) (*models.UserIdentity, error) {
+	existing, err := s.identityRepo.GetByProviderSubject(identity.Provider, identity.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, repositories.ErrUserIdentityNotFound) {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	linked := &models.UserIdentity{
+		UserID:        userID,
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+	}
+	if err := s.identityRepo.Create(linked); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditIdentityLinked,
+		ActorID: &userID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("linked %s account %s", identity.Provider, identity.Email),
+	})
+	return linked, nil
+}
+
+// UnlinkIdentity ยกเลิกการผูก provider ถ้ายังเหลือวิธี login อื่น
+func (s *identityService) UnlinkIdentity(userID, id uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	identities, err := s.identityRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	var target *models.UserIdentity
+	for _, identity := range identities {
+		if identity.ID == id {
+			target = identity
+			break
+		}
+	}
+	if target == nil {
+		return repositories.ErrUserIdentityNotFound
+	}
+
+	methods, err := countLoginMethods(user, s.identityRepo, s.credentialRepo)
+	if err != nil {
+		return err
+	}
+	if methods <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.identityRepo.Delete(userID, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditIdentityRemoved,
+		ActorID: &userID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("unlinked %s account %s", target.Provider, target.Email),
+	})
+	return nil
+}
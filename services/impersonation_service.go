@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrImpersonateSelf admin ปลอมเป็นตัวเองไม่ได้
+	ErrImpersonateSelf = errors.New("cannot impersonate yourself")
+	// ErrImpersonationNotAllowed user เป้าหมายมี permission ที่ admin ไม่มี
+	ErrImpersonationNotAllowed = errors.New("cannot impersonate a user with permissions you do not have")
+	// ErrNotImpersonating token ปัจจุบันไม่ใช่ token ของการ impersonate
+	ErrNotImpersonating = errors.New("current token is not an impersonation token")
+)
+
+// ImpersonationTTL อายุของ token ที่ใช้ดูระบบในฐานะ user อื่น (ไม่มี refresh token)
+const ImpersonationTTL = 15 * time.Minute
+
+// ImpersonationResult token สำหรับ impersonate และ user เป้าหมาย
+type ImpersonationResult struct {
+	User        *models.User `json:"user"`
+	Token       string       `json:"token"`
+	TokenExpiry int64        `json:"token_expiry"`
+}
+
+// ImpersonationService interface สำหรับให้ admin ดูระบบในฐานะ user อื่น
+type ImpersonationService interface {
+	StartImpersonation(actorID, organizationID, userID uint, reason, ip string) (*ImpersonationResult, error)
+	StopImpersonation(claims *utils.JWTClaims, ip string) error
+}
+
+// impersonationService struct implements ImpersonationService interface
+type impersonationService struct {
+	keys        *utils.KeyManager
+	userRepo    repositories.UserRepository
+	roleRepo    repositories.RoleRepository
+	memberships repositories.MembershipRepository
+	revocations TokenRevocationService
+	audit       AuditService
+}
+
+// NewImpersonationService creates new impersonation service instance
+func NewImpersonationService(keys *utils.KeyManager, userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, memberships repositories.MembershipRepository, revocations TokenRevocationService, audit AuditService) ImpersonationService {
+	return &impersonationService{
+		keys:        keys,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		memberships: memberships,
+		revocations: revocations,
+		audit:       audit,
+	}
+}
+
+// StartImpersonation ออก JWT อายุสั้นของ user เป้าหมาย โดยมี claim act ระบุ admin ตัวจริง
+// token ใช้ organization ที่ admin ใช้งานอยู่ และ user เป้าหมายต้องเป็นสมาชิกของ organization นั้น
+func (s *impersonationService) StartImpersonation(actorID, organizationID, userID uint, reason, ip string) (*ImpersonationResult, error) {
+	if actorID == userID {
+		return nil, ErrImpersonateSelf
+	}
+	if organizationID == 0 {
+		return nil, ErrNoActiveOrganization
+	}
+	if _, err := s.memberships.Get(organizationID, userID); err != nil {
+		if errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, fmt.Errorf("%w: id %d in organization %d", repositories.ErrUserNotFound, userID, organizationID)
+		}
+		return nil, err
+	}
+
+	actor, err := s.userRepo.GetByID(actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actor: %w", err)
+	}
+	if !actor.IsActive {
+		return nil, ErrUserInactive
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	// ปลอมเป็น user ที่มีสิทธิ์มากกว่าตัวเองไม่ได้
+	actorPermissions, err := rolePermissions(s.roleRepo, actor.Role)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := rolePermissions(s.roleRepo, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		if !containsString(actorPermissions, permission) {
+			return nil, ErrImpersonationNotAllowed
+		}
+	}
+
+	jti := utils.GenerateRandomString(22)
+	expiresAt := time.Now().Add(ImpersonationTTL)
+	token, err := utils.GenerateJWT(utils.JWTClaims{
+		UserID:        user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+		Permissions:   permissions,
+		OrgID:         organizationID,
+		Act:           &utils.ActorClaim{Subject: actor.Email, UserID: actor.ID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}, s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	details := fmt.Sprintf("token %s until %s", jti, expiresAt.UTC().Format(time.RFC3339))
+	if reason = strings.TrimSpace(reason); reason != "" {
+		details += ": " + reason
+	}
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditImpersonationStarted,
+		ActorID: &actor.ID,
+		UserID:  &user.ID,
+		IP:      ip,
+		Details: details,
+	})
+
+	return &ImpersonationResult{
+		User:        user,
+		Token:       token,
+		TokenExpiry: expiresAt.Unix(),
+	}, nil
+}
+
+// StopImpersonation เพิกถอน token ของการ impersonate ก่อนหมดอายุ
+func (s *impersonationService) StopImpersonation(claims *utils.JWTClaims, ip string) error {
+	if !claims.IsImpersonated() {
+		return ErrNotImpersonating
+	}
+
+	if err := s.revocations.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	actorID := claims.Act.UserID
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditImpersonationStopped,
+		ActorID: &actorID,
+		UserID:  &claims.UserID,
+		IP:      ip,
+		Details: fmt.Sprintf("token %s", claims.ID),
+	})
+	return nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// fakeOrgMemberships membership repository ในหน่วยความจำ เรียงตามลำดับที่เข้าร่วม
+type fakeOrgMemberships struct {
+	repositories.MembershipRepository
+	list []*models.Membership
+}
+
+func (r *fakeOrgMemberships) Get(organizationID, userID uint) (*models.Membership, error) {
+	for _, membership := range r.list {
+		if membership.OrganizationID == organizationID && membership.UserID == userID {
+			return membership, nil
+		}
+	}
+	return nil, repositories.ErrMembershipNotFound
+}
+
+func (r *fakeOrgMemberships) GetDefault(userID uint) (*models.Membership, error) {
+	for _, membership := range r.list {
+		if membership.UserID == userID {
+			return membership, nil
+		}
+	}
+	return nil, repositories.ErrMembershipNotFound
+}
+
+func (r *fakeOrgMemberships) CountByRole(organizationID uint, role string) (int64, error) {
+	var count int64
+	for _, membership := range r.list {
+		if membership.OrganizationID == organizationID && membership.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeOrgMemberships) UpdateRole(organizationID, userID uint, role string) error {
+	membership, err := r.Get(organizationID, userID)
+	if err != nil {
+		return err
+	}
+	membership.Role = role
+	return nil
+}
+
+func (r *fakeOrgMemberships) Delete(organizationID, userID uint) error {
+	for i, membership := range r.list {
+		if membership.OrganizationID == organizationID && membership.UserID == userID {
+			r.list = append(r.list[:i], r.list[i+1:]...)
+			return nil
+		}
+	}
+	return repositories.ErrMembershipNotFound
+}
+
+func TestStartImpersonationUsesActorOrganization(t *testing.T) {
+	keys := newTestKeyManager(t)
+	users := &fakePasskeyUsers{users: map[uint]*models.User{
+		1: {ID: 1, Email: "admin@example.com", Role: "admin", IsActive: true},
+		2: {ID: 2, Email: "user@example.com", Role: "member", IsActive: true},
+	}}
+	roles := &fakeRoles{permissions: map[string][]string{
+		"admin":  {models.PermUsersRead, models.PermUsersUpdate},
+		"member": {models.PermUsersRead},
+	}}
+
+	tests := []struct {
+		name           string
+		organizationID uint
+		// memberships ของ user 2 (organization แรกคือ organization เริ่มต้น)
+		memberships []uint
+		wantErr     error
+	}{
+		{name: "target's default organization differs", organizationID: 10, memberships: []uint{20, 10}},
+		{name: "target outside the admin's organization", organizationID: 10, memberships: []uint{20}, wantErr: repositories.ErrUserNotFound},
+		{name: "no active organization", organizationID: 0, memberships: []uint{20}, wantErr: ErrNoActiveOrganization},
+	}
+
+	for _, tt := range tests {
+		memberships := &fakeOrgMemberships{}
+		for _, organizationID := range tt.memberships {
+			memberships.list = append(memberships.list, &models.Membership{OrganizationID: organizationID, UserID: 2, Role: models.OrgRoleMember})
+		}
+		service := NewImpersonationService(keys, users, roles, memberships, nil, &fakeAudit{})
+
+		result, err := service.StartImpersonation(1, tt.organizationID, 2, "", "192.0.2.1")
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: StartImpersonation error: %v", tt.name, err)
+		}
+
+		claims, err := utils.ValidateJWT(result.Token, keys)
+		if err != nil {
+			t.Fatalf("%s: ValidateJWT error: %v", tt.name, err)
+		}
+		if claims.OrgID != tt.organizationID {
+			t.Errorf("%s: org_id = %d, want %d", tt.name, claims.OrgID, tt.organizationID)
+		}
+		if claims.UserID != 2 || claims.Act == nil || claims.Act.UserID != 1 {
+			t.Errorf("%s: claims = user %d act %+v, want user 2 acted by 1", tt.name, claims.UserID, claims.Act)
+		}
+	}
+}
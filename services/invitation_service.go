@@ -0,0 +1,306 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collp-backend/mailer"
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+var (
+	// ErrInvalidInvitation token คำเชิญไม่ถูกต้อง หมดอายุ ถูกยกเลิก หรือตอบรับไปแล้ว
+	ErrInvalidInvitation = errors.New("invalid, expired, revoked or already accepted invitation")
+	// ErrInvitationEmailMismatch คำเชิญส่งถึง email อื่น ไม่ใช่ของ user ที่ตอบรับ
+	ErrInvitationEmailMismatch = errors.New("this invitation was sent to a different email address")
+	// ErrInvitationExists มีคำเชิญที่ยังรอตอบรับของ email นี้อยู่แล้ว
+	ErrInvitationExists = errors.New("a pending invitation for this email already exists, resend it instead")
+	// ErrInvitationNotPending คำเชิญถูกตอบรับหรือยกเลิกไปแล้ว
+	ErrInvitationNotPending = errors.New("invitation has already been accepted or revoked")
+)
+
+// InvitationTTL อายุของลิงก์คำเชิญ
+const InvitationTTL = 7 * 24 * time.Hour
+
+// InvitationService interface สำหรับเชิญ email เข้า organization
+type InvitationService interface {
+	CreateInvitation(actorID, organizationID uint, email, role string) (*models.Invitation, error)
+	ListInvitations(actorID, organizationID uint) ([]*models.Invitation, error)
+	ResendInvitation(actorID, organizationID, id uint) (*models.Invitation, error)
+	RevokeInvitation(actorID, organizationID, id uint) error
+	GetInvitation(token string) (*models.Invitation, error)
+	AcceptInvitation(token string, user *models.User) (*models.Invitation, error)
+}
+
+// invitationService struct implements InvitationService interface
+type invitationService struct {
+	userRepo         repositories.UserRepository
+	organizationRepo repositories.OrganizationRepository
+	membershipRepo   repositories.MembershipRepository
+	invitationRepo   repositories.InvitationRepository
+	audit            AuditService
+	mail             mailer.Mailer
+	// inviteURL หน้า frontend ที่รับ ?token= แล้วให้ user สมัครหรือ login เพื่อตอบรับคำเชิญ
+	inviteURL string
+}
+
+// NewInvitationService creates new invitation service instance
+func NewInvitationService(userRepo repositories.UserRepository, organizationRepo repositories.OrganizationRepository, membershipRepo repositories.MembershipRepository, invitationRepo repositories.InvitationRepository, audit AuditService, mail mailer.Mailer, inviteURL string) InvitationService {
+	return &invitationService{
+		userRepo:         userRepo,
+		organizationRepo: organizationRepo,
+		membershipRepo:   membershipRepo,
+		invitationRepo:   invitationRepo,
+		audit:            audit,
+		mail:             mail,
+		inviteURL:        inviteURL,
+	}
+}
+
+// CreateInvitation เชิญ email เข้า organization ด้วย role ที่กำหนด (role ว่างเป็น member) แล้วส่งลิงก์ทาง email
+func (s *invitationService) CreateInvitation(actorID, organizationID uint, email, role string) (*models.Invitation, error) {
+	email = utils.SanitizeString(email)
+	if !utils.IsValidEmail(email) {
+		return nil, fmt.Errorf("%w: invalid email format: %s", ErrInvalidInput, email)
+	}
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	if !models.ValidOrgRole(role) {
+		return nil, ErrInvalidOrgRole
+	}
+
+	if _, err := memberManager(s.membershipRepo, actorID, organizationID, role); err != nil {
+		return nil, err
+	}
+
+	// user ที่มีบัญชีและเป็นสมาชิกอยู่แล้วไม่ต้องเชิญ
+	if user, err := s.userRepo.GetByEmail(email); err == nil {
+		if _, err := s.membershipRepo.Get(organizationID, user.ID); err == nil {
+			return nil, ErrAlreadyMember
+		} else if !errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, err
+		}
+	} else if !errors.Is(err, repositories.ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.invitationRepo.ExpirePending(organizationID); err != nil {
+		return nil, err
+	}
+	if _, err := s.invitationRepo.GetPendingByEmail(organizationID, email); err == nil {
+		return nil, ErrInvitationExists
+	} else if !errors.Is(err, repositories.ErrInvitationNotFound) {
+		return nil, err
+	}
+
+	// token สุ่ม 256 bit ส่งให้ผู้ถูกเชิญ ส่วนฐานข้อมูลเก็บแค่ hash
+	token := utils.GenerateRandomString(43)
+	now := time.Now()
+	invitation := &models.Invitation{
+		OrganizationID: organizationID,
+		Email:          email,
+		Role:           role,
+		Status:         models.InvitationPending,
+		TokenHash:      utils.HashToken(token),
+		InvitedByID:    actorID,
+		ExpiresAt:      now.Add(InvitationTTL),
+		LastSentAt:     now,
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+
+	// ส่งไม่สำเร็จต้องลบคำเชิญทิ้ง ไม่อย่างนั้นคำเชิญที่ไม่มีใครได้ลิงก์จะกันไม่ให้เชิญ email นี้ใหม่ (ErrInvitationExists)
+	if err := s.send(invitation, token); err != nil {
+		if deleteErr := s.invitationRepo.Delete(invitation.ID); deleteErr != nil {
+			return nil, fmt.Errorf("%w (and failed to remove the invitation: %v)", err, deleteErr)
+		}
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditInvitationSent,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("organization %d: invitation %d to %s as %s", organizationID, invitation.ID, email, role),
+	})
+	return invitation, nil
+}
+
+// ListInvitations ดึงคำเชิญทั้งหมดของ organization (owner/admin ของ organization)
+func (s *invitationService) ListInvitations(actorID, organizationID uint) ([]*models.Invitation, error) {
+	if _, err := memberManager(s.membershipRepo, actorID, organizationID, ""); err != nil {
+		return nil, err
+	}
+	if err := s.invitationRepo.ExpirePending(organizationID); err != nil {
+		return nil, err
+	}
+	return s.invitationRepo.GetByOrganizationID(organizationID)
+}
+
+// ResendInvitation ออก token ใหม่และส่งคำเชิญที่ยังรอหรือหมดอายุแล้วอีกครั้ง (ลิงก์เดิมใช้ไม่ได้)
+func (s *invitationService) ResendInvitation(actorID, organizationID, id uint) (*models.Invitation, error) {
+	invitation, err := s.managedInvitation(actorID, organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != models.InvitationPending && invitation.Status != models.InvitationExpired {
+		return nil, ErrInvitationNotPending
+	}
+
+	token := utils.GenerateRandomString(43)
+	expiresAt := time.Now().Add(InvitationTTL)
+	if err := s.invitationRepo.Reissue(invitation.ID, actorID, utils.HashToken(token), expiresAt); err != nil {
+		if errors.Is(err, repositories.ErrInvitationNotFound) {
+			return nil, ErrInvitationNotPending
+		}
+		return nil, err
+	}
+	invitation.Status = models.InvitationPending
+	invitation.TokenHash = utils.HashToken(token)
+	invitation.InvitedByID = actorID
+	invitation.ExpiresAt = expiresAt
+	invitation.LastSentAt = time.Now()
+
+	if err := s.send(invitation, token); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditInvitationSent,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("organization %d: invitation %d resent to %s", organizationID, invitation.ID, invitation.Email),
+	})
+	return invitation, nil
+}
+
+// managedInvitation หาคำเชิญที่ผู้เรียกจัดการได้ (คำเชิญ role owner จัดการได้เฉพาะ owner เช่นเดียวกับการให้ role owner)
+func (s *invitationService) managedInvitation(actorID, organizationID, id uint) (*models.Invitation, error) {
+	actor, err := memberManager(s.membershipRepo, actorID, organizationID, "")
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := s.invitationRepo.GetByID(organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Role == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner {
+		return nil, ErrOwnerRoleRequired
+	}
+	return invitation, nil
+}
+
+// RevokeInvitation ยกเลิกคำเชิญที่ยังรอตอบรับ
+func (s *invitationService) RevokeInvitation(actorID, organizationID, id uint) error {
+	invitation, err := s.managedInvitation(actorID, organizationID, id)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.invitationRepo.Revoke(organizationID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationNotPending
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditInvitationRevoked,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("organization %d: invitation %d to %s", organizationID, invitation.ID, invitation.Email),
+	})
+	return nil
+}
+
+// GetInvitation หาคำเชิญที่ยังรอตอบรับจาก token ในลิงก์ พร้อม organization (ให้ frontend แสดงก่อนสมัครหรือ login)
+func (s *invitationService) GetInvitation(token string) (*models.Invitation, error) {
+	if token == "" {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitation, err := s.invitationRepo.GetByTokenHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvitationNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	if invitation.Status != models.InvitationPending {
+		return nil, ErrInvalidInvitation
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		if err := s.invitationRepo.ExpirePending(invitation.OrganizationID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+// AcceptInvitation ตอบรับคำเชิญแล้วเพิ่ม user เข้า organization (email ของ user ต้องตรงกับที่ถูกเชิญ)
+// การเปิดลิงก์ในคำเชิญยืนยันว่า user เป็นเจ้าของ email จึงถือว่า email ยืนยันแล้ว
+func (s *invitationService) AcceptInvitation(token string, user *models.User) (*models.Invitation, error) {
+	invitation, err := s.GetInvitation(token)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	accepted, err := s.invitationRepo.Accept(invitation, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidInvitation
+	}
+	invitation.Status = models.InvitationAccepted
+	invitation.AcceptedUserID = &user.ID
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.userRepo.UpdateFields(user.ID, map[string]interface{}{"email_verified_at": now}); err != nil {
+			return nil, fmt.Errorf("failed to mark email as verified: %w", err)
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditInvitationAccepted,
+		ActorID: &user.ID,
+		UserID:  &user.ID,
+		Details: fmt.Sprintf("organization %d: invitation %d as %s", invitation.OrganizationID, invitation.ID, invitation.Role),
+	})
+	return invitation, nil
+}
+
+// send ส่งลิงก์คำเชิญทาง email
+func (s *invitationService) send(invitation *models.Invitation, token string) error {
+	organization, err := s.organizationRepo.GetByID(invitation.OrganizationID)
+	if err != nil {
+		return err
+	}
+	link, err := withTokenParam(s.inviteURL, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mail.Send(mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s on CollP", organization.Name),
+		Body: fmt.Sprintf("Hi,\n\nYou have been invited to join %s on CollP as %s. Open the link below to sign in or create an account and accept the invitation:\n\n%s\n\nThe link expires in %d days. If you were not expecting this invitation, you can ignore this email.\n",
+			organization.Name, invitation.Role, link, int(InvitationTTL.Hours()/24)),
+	}); err != nil {
+		return fmt.Errorf("failed to send invitation email: %w", err)
+	}
+	return nil
+}
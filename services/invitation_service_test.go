@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"collp-backend/mailer"
+	"collp-backend/models"
+	"collp-backend/repositories"
+)
+
+// fakeInvitations invitation repository ในหน่วยความจำ
+type fakeInvitations struct {
+	repositories.InvitationRepository
+	list []*models.Invitation
+}
+
+func (r *fakeInvitations) ExpirePending(organizationID uint) error {
+	return nil
+}
+
+func (r *fakeInvitations) GetPendingByEmail(organizationID uint, email string) (*models.Invitation, error) {
+	for _, invitation := range r.list {
+		if invitation.OrganizationID == organizationID && invitation.Email == email {
+			return invitation, nil
+		}
+	}
+	return nil, repositories.ErrInvitationNotFound
+}
+
+func (r *fakeInvitations) Create(invitation *models.Invitation) error {
+	invitation.ID = uint(len(r.list) + 1)
+	r.list = append(r.list, invitation)
+	return nil
+}
+
+func (r *fakeInvitations) Delete(id uint) error {
+	for i, invitation := range r.list {
+		if invitation.ID == id {
+			r.list = append(r.list[:i], r.list[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// fakeMemberships ผู้เรียกเป็น owner ของทุก organization
+type fakeMemberships struct {
+	repositories.MembershipRepository
+}
+
+func (fakeMemberships) Get(organizationID, userID uint) (*models.Membership, error) {
+	if userID != 1 {
+		return nil, repositories.ErrMembershipNotFound
+	}
+	return &models.Membership{OrganizationID: organizationID, UserID: userID, Role: models.OrgRoleOwner}, nil
+}
+
+type fakeOrganizations struct {
+	repositories.OrganizationRepository
+}
+
+func (fakeOrganizations) GetByID(id uint) (*models.Organization, error) {
+	return &models.Organization{ID: id, Name: "Acme"}, nil
+}
+
+// fakeMailer บันทึกอีเมลที่ส่ง หรือคืน err
+type fakeMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestCreateInvitationSendFailure(t *testing.T) {
+	invitations := &fakeInvitations{}
+	mail := &fakeMailer{err: errors.New("smtp unavailable")}
+	service := NewInvitationService(&fakeEmailUsers{}, fakeOrganizations{}, fakeMemberships{}, invitations, &fakeAudit{}, mail, "https://app.example.com/invite")
+
+	if _, err := service.CreateInvitation(1, 5, "new@example.com", ""); err == nil {
+		t.Fatal("CreateInvitation succeeded although the email was not sent")
+	}
+	if len(invitations.list) != 0 {
+		t.Fatalf("invitation kept after failed send: %+v", invitations.list[0])
+	}
+
+	// เชิญใหม่ได้หลังส่งไม่สำเร็จ
+	mail.err = nil
+	invitation, err := service.CreateInvitation(1, 5, "new@example.com", "")
+	if err != nil {
+		t.Fatalf("CreateInvitation after failed send: %v", err)
+	}
+	if invitation.Status != models.InvitationPending || len(mail.sent) != 1 || mail.sent[0].To != "new@example.com" {
+		t.Errorf("invitation = %+v, sent = %v, want pending invitation emailed to new@example.com", invitation, mail.sent)
+	}
+}
+
+// fakeInvitationService บันทึก token ที่ถูกตอบรับ และคืน err จาก AcceptInvitation
+type fakeInvitationService struct {
+	InvitationService
+	err      error
+	accepted []string
+}
+
+func (s *fakeInvitationService) AcceptInvitation(token string, user *models.User) (*models.Invitation, error) {
+	s.accepted = append(s.accepted, token)
+	return &models.Invitation{}, s.err
+}
+
+func TestAcceptInvitationDoesNotFailLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		err       error
+		wantError string
+	}{
+		{name: "no invitation", token: ""},
+		{name: "accepted", token: "token"},
+		{name: "invalid invitation", token: "token", err: ErrInvalidInvitation, wantError: ErrInvalidInvitation.Error()},
+		{name: "other email", token: "token", err: ErrInvitationEmailMismatch, wantError: ErrInvitationEmailMismatch.Error()},
+		{name: "database error", token: "token", err: errors.New("connection reset"), wantError: "failed to accept invitation"},
+	}
+
+	for _, tt := range tests {
+		service := &AuthService{invitations: &fakeInvitationService{err: tt.err}}
+		if got := service.acceptInvitation(tt.token, &models.User{ID: 7}); got != tt.wantError {
+			t.Errorf("%s: invitation error = %q, want %q", tt.name, got, tt.wantError)
+		}
+	}
+}
+
+func TestCompleteLoginKeepsInvitationUntilMFA(t *testing.T) {
+	invitations := &fakeInvitationService{}
+	service := &AuthService{
+		invitations:   invitations,
+		mfaChallenges: newOneTimeStore[*mfaChallenge](MFAChallengeTTL),
+	}
+	enabledAt := time.Now()
+
+	result, err := service.completeLogin(&models.User{ID: 7, TOTPEnabledAt: &enabledAt}, ClientInfo{}, "invitation-token")
+	if err != nil {
+		t.Fatalf("completeLogin error: %v", err)
+	}
+	if !result.MFARequired || result.Token != "" {
+		t.Fatalf("result = %+v, want MFA challenge", result)
+	}
+	if len(invitations.accepted) != 0 {
+		t.Fatal("invitation accepted before the second factor was verified")
+	}
+
+	challenge, err := service.takeMFAChallenge(result.MFAToken)
+	if err != nil {
+		t.Fatalf("takeMFAChallenge error: %v", err)
+	}
+	if challenge.invitation != "invitation-token" {
+		t.Errorf("challenge invitation = %q, want %q", challenge.invitation, "invitation-token")
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"collp-backend/models"
+)
+
+// acceptInvitation ตอบรับคำเชิญที่มากับการ login หรือสมัคร ก่อนออก token เพื่อให้ organization ใหม่อยู่ใน session
+// คำเชิญที่ใช้ไม่ได้ไม่ทำให้ login ล้มเหลว คืนเหตุผลไว้ใส่ใน AuthResult.InvitationError แทน
+func (s *AuthService) acceptInvitation(token string, user *models.User) string {
+	if token == "" {
+		return ""
+	}
+	_, err := s.invitations.AcceptInvitation(token, user)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInvalidInvitation), errors.Is(err, ErrInvitationEmailMismatch):
+		return err.Error()
+	default:
+		log.Printf("Failed to accept invitation for user %d: %v", user.ID, err)
+		return "failed to accept invitation"
+	}
+}
+
+// issueTokenWithInvitation ตอบรับคำเชิญแล้วออก JWT เรียกหลังผ่านทุกปัจจัยของการ login แล้วเท่านั้น
+func (s *AuthService) issueTokenWithInvitation(user *models.User, client ClientInfo, invitation string) (*AuthResult, error) {
+	invitationError := s.acceptInvitation(invitation, user)
+	result, err := s.issueToken(user, client)
+	if err != nil {
+		return nil, err
+	}
+	result.InvitationError = invitationError
+	return result, nil
+}
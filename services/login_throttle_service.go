@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+)
+
+// ErrTooManyLoginAttempts login ผิดบ่อยเกินไป ต้องรอก่อนลองใหม่
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// LoginThrottledError บอกว่าต้องรออีกนานเท่าไรจึงจะ login ได้
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottlePolicy กำหนดการหน่วงเวลาและล็อคเมื่อ login ผิด
+type LoginThrottlePolicy struct {
+	// MaxAccountFailures จำนวนครั้งที่ผิดติดกันก่อนล็อคบัญชี
+	MaxAccountFailures int
+	// MaxIPFailures จำนวนครั้งที่ผิดจาก IP เดียว (ทุกบัญชีรวมกัน) ก่อนล็อค IP
+	MaxIPFailures int
+	// FreeAttempts จำนวนครั้งที่ผิดได้โดยยังไม่ถูกหน่วงเวลา
+	FreeAttempts int
+	// MaxDelay เวลาหน่วงสูงสุดระหว่างครั้งที่ผิด (เพิ่มเป็นเท่าตัวจาก 1 วินาที)
+	MaxDelay time.Duration
+	// FailureWindow ถ้าไม่ผิดเลยภายในช่วงนี้จะเริ่มนับใหม่
+	FailureWindow time.Duration
+	// LockoutDuration ระยะเวลาที่ล็อค
+	LockoutDuration time.Duration
+}
+
+// DefaultLoginThrottlePolicy ค่าเริ่มต้น: ล็อคบัญชี 15 นาทีหลังผิด 5 ครั้ง, ล็อค IP หลังผิด 20 ครั้ง
+func DefaultLoginThrottlePolicy() LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		FreeAttempts:       2,
+		MaxDelay:           30 * time.Second,
+		FailureWindow:      15 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+	}
+}
+
+// LoadLoginThrottlePolicy อ่าน LOGIN_MAX_ACCOUNT_FAILURES, LOGIN_MAX_IP_FAILURES และ LOGIN_LOCKOUT_DURATION
+func LoadLoginThrottlePolicy() (LoginThrottlePolicy, error) {
+	policy := DefaultLoginThrottlePolicy()
+
+	for env, target := range map[string]*int{
+		"LOGIN_MAX_ACCOUNT_FAILURES": &policy.MaxAccountFailures,
+		"LOGIN_MAX_IP_FAILURES":      &policy.MaxIPFailures,
+	} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return LoginThrottlePolicy{}, fmt.Errorf("invalid %s: %q", env, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := os.Getenv("LOGIN_LOCKOUT_DURATION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return LoginThrottlePolicy{}, fmt.Errorf("invalid LOGIN_LOCKOUT_DURATION: %q", value)
+		}
+		policy.LockoutDuration = parsed
+	}
+
+	return policy, nil
+}
+
+// delay เวลาที่ต้องรอหลังผิดครั้งที่ failures: 1s, 2s, 4s, ... ไม่เกิน MaxDelay
+func (p LoginThrottlePolicy) delay(failures int) time.Duration {
+	extra := failures - p.FreeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	if extra > 16 {
+		return p.MaxDelay
+	}
+	delay := time.Second << (extra - 1)
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// LoginThrottleService interface สำหรับป้องกันการเดา password (ต่อบัญชีและต่อ IP)
+type LoginThrottleService interface {
+	// Check คืน *LoginThrottledError ถ้าบัญชีหรือ IP ยังต้องรอ (user เป็น nil ได้ถ้าไม่พบ email)
+	Check(user *models.User, ip string) error
+	RecordFailure(user *models.User, ip string) error
+	RecordSuccess(user *models.User) error
+}
+
+// loginThrottleService struct implements LoginThrottleService interface
+type loginThrottleService struct {
+	policy      LoginThrottlePolicy
+	userRepo    repositories.UserRepository
+	failureRepo repositories.LoginFailureRepository
+	audit       AuditService
+}
+
+// NewLoginThrottleService creates new login throttle service instance
+func NewLoginThrottleService(policy LoginThrottlePolicy, userRepo repositories.UserRepository, failureRepo repositories.LoginFailureRepository, audit AuditService) LoginThrottleService {
+	return &loginThrottleService{
+		policy:      policy,
+		userRepo:    userRepo,
+		failureRepo: failureRepo,
+		audit:       audit,
+	}
+}
+
+// Check ตรวจสอบการล็อคของ IP และบัญชี รวมถึงเวลาหน่วงหลัง login ผิด
+func (s *loginThrottleService) Check(user *models.User, ip string) error {
+	now := time.Now()
+
+	failure, err := s.failureRepo.GetByIP(ip)
+	if err != nil {
+		return err
+	}
+	if failure != nil && failure.LockedUntil != nil && now.Before(*failure.LockedUntil) {
+		return &LoginThrottledError{RetryAfter: failure.LockedUntil.Sub(now)}
+	}
+
+	if user == nil {
+		return nil
+	}
+	if user.IsLocked(now) {
+		return &LoginThrottledError{RetryAfter: user.LockedUntil.Sub(now)}
+	}
+	if user.LastFailedLoginAt != nil {
+		allowAt := user.LastFailedLoginAt.Add(s.policy.delay(user.FailedLoginAttempts))
+		if now.Before(allowAt) {
+			return &LoginThrottledError{RetryAfter: allowAt.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure นับ login ที่ผิดและล็อคบัญชีหรือ IP เมื่อเกินกำหนด
+func (s *loginThrottleService) RecordFailure(user *models.User, ip string) error {
+	now := time.Now()
+	lockedUntil := now.Add(s.policy.LockoutDuration)
+
+	ipFailures, err := s.failureRepo.RecordFailure(ip, now, s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if ipFailures >= s.policy.MaxIPFailures {
+		if err := s.failureRepo.LockIP(ip, lockedUntil); err != nil {
+			return err
+		}
+		if ipFailures == s.policy.MaxIPFailures {
+			s.audit.Record(&models.AuditLog{
+				Action:  models.AuditLoginIPLocked,
+				IP:      ip,
+				Details: fmt.Sprintf("%d failed login attempts, locked until %s", ipFailures, lockedUntil.Format(time.RFC3339)),
+			})
+		}
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	accountFailures, err := s.userRepo.RecordFailedLogin(user.ID, now, s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if accountFailures >= s.policy.MaxAccountFailures {
+		if err := s.userRepo.LockUntil(user.ID, lockedUntil); err != nil {
+			return err
+		}
+		log.Printf("User %d locked until %s after %d failed login attempts", user.ID, lockedUntil.Format(time.RFC3339), accountFailures)
+		s.audit.Record(&models.AuditLog{
+			Action:  models.AuditAccountLocked,
+			UserID:  &user.ID,
+			IP:      ip,
+			Details: fmt.Sprintf("%d failed login attempts, locked until %s", accountFailures, lockedUntil.Format(time.RFC3339)),
+		})
+	}
+
+	// ลบ IP ที่เงียบไปนานแล้วเป็นระยะ
+	if ipFailures == 1 {
+		if err := s.failureRepo.DeleteStale(now.Add(-s.policy.FailureWindow)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess ล้างจำนวน login ผิดของบัญชีหลัง login สำเร็จ
+// (ไม่ล้างของ IP เพื่อไม่ให้บัญชีที่รู้ password ใช้ล้างโควต้าการเดาบัญชีอื่น)
+func (s *loginThrottleService) RecordSuccess(user *models.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	return s.userRepo.ResetFailedLogins(user.ID)
+}
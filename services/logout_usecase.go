@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// ErrLogoutAllNotAllowed token ของ OAuth client หรือของการ impersonate ใช้ logout ทุกอุปกรณ์ไม่ได้
+var ErrLogoutAllNotAllowed = errors.New("logout from all devices is not allowed for this token")
+
+// parseAccessToken ตรวจสอบ access token และต้องยังไม่ถูกเพิกถอน
+func (s *AuthService) parseAccessToken(accessToken string) (*utils.JWTClaims, error) {
+	claims, err := utils.ValidateJWT(accessToken, s.keys)
+	if err != nil || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	revoked, err := s.revocations.IsTokenRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidAccessToken
+	}
+
+	return claims, nil
+}
+
+// Logout เพิกถอน access token ปัจจุบัน และ refresh token family ถ้าส่งมาด้วย
+func (s *AuthService) Logout(accessToken, refreshToken string) error {
+	claims, err := s.parseAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.revocations.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.refreshRepo.GetByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	if stored.UserID != claims.UserID {
+		return nil
+	}
+
+	return s.refreshRepo.RevokeFamily(stored.FamilyID)
+}
+
+// LogoutAll ออกจากระบบทุกอุปกรณ์: เพิกถอน access token และ refresh token ทั้งหมดของ user
+func (s *AuthService) LogoutAll(accessToken string) error {
+	claims, err := s.parseAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+	// token ของ OAuth client ไม่มี user (UserID 0 ใช้ร่วมกันทุก client)
+	// และ admin ที่ impersonate อยู่ต้องไม่ทำให้ user ตัวจริงหลุดจากทุกอุปกรณ์
+	if claims.IsServiceClient() || claims.IsImpersonated() {
+		return ErrLogoutAllNotAllowed
+	}
+
+	return s.revocations.RevokeAllForUser(claims.UserID)
+}
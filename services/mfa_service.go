@@ -0,0 +1,342 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+
+	"github.com/skip2/go-qrcode"
+)
+
+var (
+	// ErrInvalidMFACode TOTP code หรือรหัสสำรองไม่ถูกต้อง
+	ErrInvalidMFACode = errors.New("invalid two-factor authentication code")
+	// ErrMFANotEnabled user ยังไม่ได้เปิด 2FA
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrMFAAlreadyEnabled user เปิด 2FA อยู่แล้ว
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFAEnrollmentNotStarted ต้องเริ่มลงทะเบียน TOTP ก่อนยืนยัน
+	ErrMFAEnrollmentNotStarted = errors.New("two-factor enrollment has not been started")
+	// ErrMFARequiredByRole role ของ user บังคับ 2FA จึงปิดไม่ได้
+	ErrMFARequiredByRole = errors.New("two-factor authentication is required for this role")
+)
+
+const (
+	// recoveryCodeCount จำนวนรหัสสำรองที่ออกให้แต่ละครั้ง
+	recoveryCodeCount = 10
+	// totpQRCodeSize ขนาด QR code PNG (pixels)
+	totpQRCodeSize = 256
+)
+
+// TOTPEnrollment ข้อมูลสำหรับเพิ่มบัญชีใน authenticator app
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRCodePNG data URI ของ QR code (image/png) ที่มี OTPAuthURI
+	QRCodePNG string `json:"qr_code_png"`
+}
+
+// MFAStatus สถานะ 2FA ของ user
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	Required               bool       `json:"required"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MFAService interface สำหรับ TOTP two-factor authentication
+type MFAService interface {
+	GetStatus(userID uint) (*MFAStatus, error)
+	IsRequired(user *models.User) (bool, error)
+	BeginTOTPEnrollment(userID uint) (*TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(userID uint, code string) ([]string, error)
+	DisableTOTP(userID uint, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	VerifyCode(user *models.User, code string) (bool, error)
+	SetRoleRequirement(roleName string, required bool, actorID uint) error
+}
+
+// mfaService struct implements MFAService interface
+type mfaService struct {
+	userRepo     repositories.UserRepository
+	roleRepo     repositories.RoleRepository
+	recoveryRepo repositories.RecoveryCodeRepository
+	audit        AuditService
+	// issuer ชื่อที่แสดงใน authenticator app
+	issuer string
+}
+
+// NewMFAService creates new MFA service instance
+func NewMFAService(userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, recoveryRepo repositories.RecoveryCodeRepository, audit AuditService, issuer string) MFAService {
+	if issuer == "" {
+		issuer = "CollP"
+	}
+	return &mfaService{
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		recoveryRepo: recoveryRepo,
+		audit:        audit,
+		issuer:       issuer,
+	}
+}
+
+// GetStatus ดึงสถานะ 2FA ของ user
+func (s *mfaService) GetStatus(userID uint) (*MFAStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	required, err := s.IsRequired(user)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := s.recoveryRepo.CountUnused(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAStatus{
+		Enabled:                user.MFAEnabled(),
+		EnabledAt:              user.TOTPEnabledAt,
+		Required:               required,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// IsRequired ตรวจสอบว่า role ของ user บังคับ 2FA หรือไม่
+func (s *mfaService) IsRequired(user *models.User) (bool, error) {
+	role, err := s.roleRepo.GetByName(user.Role)
+	if err != nil {
+		if errors.Is(err, repositories.ErrRoleNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get role: %w", err)
+	}
+	return role.RequireMFA, nil
+}
+
+// BeginTOTPEnrollment สร้าง secret ใหม่ (ยังไม่เปิดใช้จนกว่าจะยืนยันด้วย code)
+func (s *mfaService) BeginTOTPEnrollment(userID uint) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateFields(userID, map[string]interface{}{"totp_secret": secret}); err != nil {
+		return nil, fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+
+	uri := utils.TOTPURI(s.issuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, totpQRCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCodePNG:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment เปิดใช้ 2FA เมื่อ code จาก authenticator ถูกต้อง แล้วคืนรหัสสำรองชุดใหม่
+func (s *mfaService) ConfirmTOTPEnrollment(userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastUsedStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.userRepo.UpdateFields(userID, map[string]interface{}{
+		"totp_enabled_at":     time.Now(),
+		"totp_last_used_step": step,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+
+	codes, err := s.issueRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMFAEnabled,
+		ActorID: &userID,
+		UserID:  &userID,
+	})
+
+	return codes, nil
+}
+
+// DisableTOTP ปิด 2FA (ต้องยืนยันด้วย code และ role ต้องไม่บังคับ 2FA)
+func (s *mfaService) DisableTOTP(userID uint, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.MFAEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	required, err := s.IsRequired(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByRole
+	}
+
+	ok, err := s.VerifyCode(user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	if err := s.userRepo.UpdateFields(userID, map[string]interface{}{
+		"totp_secret":         "",
+		"totp_enabled_at":     nil,
+		"totp_last_used_step": 0,
+	}); err != nil {
+		return fmt.Errorf("failed to disable TOTP: %w", err)
+	}
+	if err := s.recoveryRepo.DeleteForUser(userID); err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMFADisabled,
+		ActorID: &userID,
+		UserID:  &userID,
+	})
+
+	return nil
+}
+
+// RegenerateRecoveryCodes ออกรหัสสำรองชุดใหม่ (ชุดเดิมใช้ไม่ได้อีก)
+func (s *mfaService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.MFAEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	ok, err := s.VerifyCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	return s.issueRecoveryCodes(userID)
+}
+
+// VerifyCode ตรวจสอบ TOTP code หรือรหัสสำรอง (แต่ละ code ใช้ได้ครั้งเดียว)
+func (s *mfaService) VerifyCode(user *models.User, code string) (bool, error) {
+	if !user.MFAEnabled() {
+		return false, ErrMFANotEnabled
+	}
+
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastUsedStep); ok {
+		recorded, err := s.userRepo.RecordTOTPStep(user.ID, step)
+		if err != nil {
+			return false, err
+		}
+		if recorded {
+			user.TOTPLastUsedStep = step
+		}
+		return recorded, nil
+	}
+
+	used, err := s.recoveryRepo.Consume(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		s.audit.Record(&models.AuditLog{
+			Action: models.AuditMFARecoveryUsed,
+			UserID: &user.ID,
+		})
+	}
+	return used, nil
+}
+
+// SetRoleRequirement กำหนดว่า role บังคับ 2FA หรือไม่
+func (s *mfaService) SetRoleRequirement(roleName string, required bool, actorID uint) error {
+	if err := s.roleRepo.SetRequireMFA(roleName, required); err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMFAPolicy,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("role %s require_mfa=%t", roleName, required),
+	})
+
+	return nil
+}
+
+// issueRecoveryCodes สร้างรหัสสำรองชุดใหม่แทนชุดเดิม คืนรหัสแบบ plain text ให้ user เก็บไว้ (แสดงครั้งเดียว)
+func (s *mfaService) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := s.recoveryRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode สุ่มรหัส 80 bit ในรูปแบบ xxxx-xxxx-xxxx-xxxx
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+	return encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16], nil
+}
+
+// normalizeRecoveryCode ไม่สนตัวพิมพ์ เว้นวรรค และขีด
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+var (
+	// ErrInvalidMFAToken mfa_token ไม่ถูกต้อง หมดอายุ หรือถูกใช้ไปแล้ว
+	ErrInvalidMFAToken = errors.New("invalid, expired or already used MFA token")
+	// ErrMFAEnrollmentRequired role บังคับ 2FA ต้องลงทะเบียน authenticator ก่อน
+	ErrMFAEnrollmentRequired = errors.New("two-factor enrollment is required before signing in")
+)
+
+const (
+	// MFAChallengeTTL อายุของ mfa_token ระหว่าง login กับการยืนยันปัจจัยที่สอง
+	MFAChallengeTTL = 5 * time.Minute
+	// maxMFAAttempts จำนวนครั้งที่ใส่ code ผิดได้ต่อ mfa_token
+	maxMFAAttempts = 5
+)
+
+// mfaChallenge สถานะของ login ที่ผ่าน password/OAuth แล้วแต่ยังรอปัจจัยที่สอง
+type mfaChallenge struct {
+	userID uint
+	// enrollment true = role บังคับ 2FA แต่ user ยังไม่ลงทะเบียน
+	enrollment bool
+	// invitation token คำเชิญที่มากับการ login ตอบรับหลังยืนยันปัจจัยที่สองแล้ว
+	invitation string
+	attempts   int
+	expiresAt  time.Time
+}
+
+// completeLogin ออก JWT ถ้าไม่ต้องใช้ 2FA ไม่อย่างนั้นคืน mfa_token ให้ยืนยันปัจจัยที่สองก่อน
+// invitation (ถ้ามี) ตอบรับตอนออก JWT เท่านั้น ไม่ใช่ตอนผ่านแค่ password
+func (s *AuthService) completeLogin(user *models.User, client ClientInfo, invitation string) (*AuthResult, error) {
+	if user.MFAEnabled() {
+		return s.createMFAChallenge(user.ID, false, invitation), nil
+	}
+
+	required, err := s.mfa.IsRequired(user)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return s.createMFAChallenge(user.ID, true, invitation), nil
+	}
+
+	return s.issueTokenWithInvitation(user, client, invitation)
+}
+
+func (s *AuthService) createMFAChallenge(userID uint, enrollment bool, invitation string) *AuthResult {
+	token := utils.GenerateRandomString(43)
+	s.mfaChallenges.Put(token, &mfaChallenge{
+		userID:     userID,
+		enrollment: enrollment,
+		invitation: invitation,
+		expiresAt:  time.Now().Add(MFAChallengeTTL),
+	})
+	return &AuthResult{
+		MFARequired:           !enrollment,
+		MFAEnrollmentRequired: enrollment,
+		MFAToken:              token,
+	}
+}
+
+// takeMFAChallenge ดึง challenge ออกจาก store (ต้อง putBack ถ้าจะให้ลองใหม่ได้)
+func (s *AuthService) takeMFAChallenge(mfaToken string) (*mfaChallenge, error) {
+	challenge, ok := s.mfaChallenges.Take(mfaToken)
+	if mfaToken == "" || !ok || time.Now().After(challenge.expiresAt) {
+		return nil, ErrInvalidMFAToken
+	}
+	return challenge, nil
+}
+
+// retryMFAChallenge คืน challenge กลับเข้า store หลังใส่ code ผิด จนกว่าจะครบจำนวนครั้ง
+func (s *AuthService) retryMFAChallenge(mfaToken string, challenge *mfaChallenge) {
+	challenge.attempts++
+	if challenge.attempts < maxMFAAttempts {
+		s.mfaChallenges.Put(mfaToken, challenge)
+	}
+}
+
+// getChallengeUser ดึง user ของ challenge ที่ยังใช้งานได้
+func (s *AuthService) getChallengeUser(challenge *mfaChallenge) (*models.User, error) {
+	user, err := s.userRepo.GetByID(challenge.userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+	return user, nil
+}
+
+// VerifyMFA ยืนยัน TOTP code หรือรหัสสำรองของ mfa_token แล้วออก JWT
+// code ที่ผิดนับรวมกับ login ผิด (LoginThrottleService)
+func (s *AuthService) VerifyMFA(mfaToken, code string, client ClientInfo) (*AuthResult, error) {
+	challenge, err := s.takeMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.enrollment {
+		s.mfaChallenges.Put(mfaToken, challenge)
+		return nil, ErrMFAEnrollmentRequired
+	}
+
+	user, err := s.getChallengeUser(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.Check(user, client.IP); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.mfa.VerifyCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.throttle.RecordFailure(user, client.IP); err != nil {
+			return nil, fmt.Errorf("failed to record failed login: %w", err)
+		}
+		s.retryMFAChallenge(mfaToken, challenge)
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.throttle.RecordSuccess(user); err != nil {
+		return nil, fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+
+	return s.issueTokenWithInvitation(user, client, challenge.invitation)
+}
+
+// BeginMFAEnrollment เริ่มลงทะเบียน TOTP ระหว่าง login เมื่อ role บังคับ 2FA
+func (s *AuthService) BeginMFAEnrollment(mfaToken string) (*TOTPEnrollment, error) {
+	challenge, err := s.takeMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	// ใช้ mfa_token เดิมยืนยันการลงทะเบียนต่อ
+	s.mfaChallenges.Put(mfaToken, challenge)
+	if !challenge.enrollment {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if _, err := s.getChallengeUser(challenge); err != nil {
+		return nil, err
+	}
+
+	return s.mfa.BeginTOTPEnrollment(challenge.userID)
+}
+
+// ConfirmMFAEnrollment ยืนยันการลงทะเบียน TOTP ระหว่าง login แล้วออก JWT พร้อมรหัสสำรอง
+func (s *AuthService) ConfirmMFAEnrollment(mfaToken, code string, client ClientInfo) (*AuthResult, error) {
+	challenge, err := s.takeMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.enrollment {
+		s.mfaChallenges.Put(mfaToken, challenge)
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.getChallengeUser(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.mfa.ConfirmTOTPEnrollment(user.ID, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrMFAEnrollmentNotStarted) {
+			s.retryMFAChallenge(mfaToken, challenge)
+		}
+		return nil, err
+	}
+
+	result, err := s.issueTokenWithInvitation(user, client, challenge.invitation)
+	if err != nil {
+		return nil, err
+	}
+	result.RecoveryCodes = recoveryCodes
+	return result, nil
+}
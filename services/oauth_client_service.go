@@ -0,0 +1,272 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidClient client_id หรือ client_secret ไม่ถูกต้อง
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidClientScope scope ที่ขอไม่ได้รับอนุญาตสำหรับ client นี้
+	ErrInvalidClientScope = errors.New("requested scope is not allowed for this client")
+	// ErrInvalidClientName ชื่อ client ว่างหรือยาวเกินไป
+	ErrInvalidClientName = errors.New("client name is required and must be at most 64 characters")
+)
+
+const (
+	// ClientAccessTokenTTL อายุ access token ของ OAuth client (ลบ client แล้ว token เดิมยังใช้ได้จนหมดอายุ)
+	ClientAccessTokenTTL = 15 * time.Minute
+	maxClientNameLength  = 64
+)
+
+// OAuthClientCredentials client ที่สร้างหรือเปลี่ยน secret ใหม่ ClientSecret แสดงได้ครั้งเดียว
+type OAuthClientCredentials struct {
+	ClientSecret string `json:"client_secret"`
+	*models.OAuthClient
+}
+
+// ClientAccessToken response ของ client_credentials grant (RFC 6749 section 5.1)
+type ClientAccessToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OAuthClientService interface สำหรับ registry ของ OAuth clients และ client_credentials grant
+type OAuthClientService interface {
+	CreateClient(actorID, organizationID uint, name string, scopes []string) (*OAuthClientCredentials, error)
+	ListClients() ([]*models.OAuthClient, error)
+	RotateSecret(actorID, id uint) (*OAuthClientCredentials, error)
+	DeleteClient(actorID, id uint) error
+	IssueToken(clientID, clientSecret, scope string) (*ClientAccessToken, error)
+}
+
+// oauthClientService struct implements OAuthClientService interface
+type oauthClientService struct {
+	keys           *utils.KeyManager
+	userRepo       repositories.UserRepository
+	roleRepo       repositories.RoleRepository
+	membershipRepo repositories.MembershipRepository
+	clientRepo     repositories.OAuthClientRepository
+	audit          AuditService
+}
+
+// NewOAuthClientService creates new OAuth client service instance
+func NewOAuthClientService(keys *utils.KeyManager, userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, membershipRepo repositories.MembershipRepository, clientRepo repositories.OAuthClientRepository, audit AuditService) OAuthClientService {
+	return &oauthClientService{
+		keys:           keys,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		membershipRepo: membershipRepo,
+		clientRepo:     clientRepo,
+		audit:          audit,
+	}
+}
+
+// CreateClient ลงทะเบียน client ใหม่ใน organization ที่ผู้สร้างใช้งานอยู่ scopes ต้องไม่เกิน permissions ของ role ผู้สร้าง
+func (s *oauthClientService) CreateClient(actorID, organizationID uint, name string, scopes []string) (*OAuthClientCredentials, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxClientNameLength {
+		return nil, ErrInvalidClientName
+	}
+
+	actor, err := s.userRepo.GetByID(actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	permissions, err := rolePermissions(s.roleRepo, actor.Role)
+	if err != nil {
+		return nil, err
+	}
+	granted := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !containsString(permissions, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTokenScope, scope)
+		}
+		if !containsString(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	secret := utils.GenerateRandomString(43)
+	client := &models.OAuthClient{
+		ClientID:   utils.GenerateRandomString(22),
+		Name:       name,
+		SecretHash: utils.HashToken(secret),
+		Scopes:     strings.Join(granted, ","),
+		CreatedBy:  &actorID,
+	}
+	if organizationID != 0 {
+		client.OrganizationID = &organizationID
+	}
+	if err := s.clientRepo.Create(client); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditClientCreated,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("client %s (%s) scopes [%s]", client.ClientID, client.Name, client.Scopes),
+	})
+	return &OAuthClientCredentials{ClientSecret: secret, OAuthClient: client}, nil
+}
+
+// ListClients ดึง clients ทั้งหมด
+func (s *oauthClientService) ListClients() ([]*models.OAuthClient, error) {
+	return s.clientRepo.GetAll()
+}
+
+// RotateSecret ออก client_secret ใหม่ secret เดิมใช้ขอ token ไม่ได้ทันที
+func (s *oauthClientService) RotateSecret(actorID, id uint) (*OAuthClientCredentials, error) {
+	client, err := s.clientRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := utils.GenerateRandomString(43)
+	client.SecretHash = utils.HashToken(secret)
+	if err := s.clientRepo.UpdateSecret(client.ID, client.SecretHash); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditClientRotated,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("client %s", client.ClientID),
+	})
+	return &OAuthClientCredentials{ClientSecret: secret, OAuthClient: client}, nil
+}
+
+// DeleteClient ลบ client
+func (s *oauthClientService) DeleteClient(actorID, id uint) error {
+	client, err := s.clientRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.clientRepo.Delete(client.ID); err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditClientDeleted,
+		ActorID: &actorID,
+		Details: fmt.Sprintf("client %s (%s)", client.ClientID, client.Name),
+	})
+	return nil
+}
+
+// allowedScopes scopes ของ client ที่ผู้สร้างยังมี permission อยู่ และ organization ของ client
+// ถ้าผู้สร้างยังเป็นสมาชิก (0 = ไม่มี) เหมือน personal access token
+// ผู้สร้างที่ถูกลบหรือปิดการใช้งานทำให้ client ขอ token ไม่ได้
+func (s *oauthClientService) allowedScopes(client *models.OAuthClient) ([]string, uint, error) {
+	if client.CreatedBy == nil {
+		return nil, 0, ErrInvalidClient
+	}
+	creator, err := s.userRepo.GetByID(*client.CreatedBy)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, 0, ErrInvalidClient
+		}
+		return nil, 0, fmt.Errorf("failed to get client creator: %w", err)
+	}
+	if !creator.IsActive {
+		return nil, 0, ErrInvalidClient
+	}
+
+	var organizationID uint
+	if client.OrganizationID != nil {
+		if _, err := s.membershipRepo.Get(*client.OrganizationID, creator.ID); err == nil {
+			organizationID = *client.OrganizationID
+		} else if !errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, 0, err
+		}
+	}
+
+	permissions, err := rolePermissions(s.roleRepo, creator.Role)
+	if err != nil {
+		return nil, 0, err
+	}
+	allowed := []string{}
+	if client.Scopes != "" {
+		for _, scope := range strings.Split(client.Scopes, ",") {
+			if containsString(permissions, scope) {
+				allowed = append(allowed, scope)
+			}
+		}
+	}
+	return allowed, organizationID, nil
+}
+
+// IssueToken ออก access token ให้ client ตาม client_credentials grant
+// scope ว่างคือขอทุก scope ที่ client ได้รับอนุญาตและผู้สร้างยังมี permission อยู่
+func (s *oauthClientService) IssueToken(clientID, clientSecret, scope string) (*ClientAccessToken, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+	client, err := s.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+	// secret ที่ถูกล้าง (เช่นหลังผู้สร้าง reset password) ใช้ไม่ได้จนกว่าจะออก secret ใหม่
+	if client.SecretHash == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	allowed, organizationID, err := s.allowedScopes(client)
+	if err != nil {
+		return nil, err
+	}
+	granted := allowed
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		granted = make([]string, 0, len(requested))
+		for _, requestedScope := range requested {
+			if !containsString(allowed, requestedScope) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidClientScope, requestedScope)
+			}
+			if !containsString(granted, requestedScope) {
+				granted = append(granted, requestedScope)
+			}
+		}
+	}
+
+	expiresAt := time.Now().Add(ClientAccessTokenTTL)
+	token, err := utils.GenerateJWT(utils.JWTClaims{
+		Permissions: granted,
+		OrgID:       organizationID,
+		TokenType:   utils.TokenTypeClient,
+		ClientID:    client.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   client.ClientID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}, s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	if err := s.clientRepo.RecordUse(client.ID); err != nil {
+		return nil, err
+	}
+
+	return &ClientAccessToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ClientAccessTokenTTL.Seconds()),
+		Scope:       strings.Join(granted, " "),
+	}, nil
+}
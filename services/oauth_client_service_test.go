@@ -0,0 +1,174 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// fakeClients OAuth client repository ที่มี client เดียว
+type fakeClients struct {
+	repositories.OAuthClientRepository
+	client *models.OAuthClient
+}
+
+func (r *fakeClients) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	if r.client == nil || r.client.ClientID != clientID {
+		return nil, repositories.ErrOAuthClientNotFound
+	}
+	return r.client, nil
+}
+
+func (r *fakeClients) RecordUse(id uint) error {
+	return nil
+}
+
+// fakeRoles permissions ของแต่ละ role
+type fakeRoles struct {
+	repositories.RoleRepository
+	permissions map[string][]string
+}
+
+func (r *fakeRoles) GetPermissionNames(roleName string) ([]string, error) {
+	permissions, ok := r.permissions[roleName]
+	if !ok {
+		return nil, repositories.ErrRoleNotFound
+	}
+	return permissions, nil
+}
+
+// newTestKeyManager สร้าง signing key ชั่วคราวสำหรับออก JWT
+func newTestKeyManager(t *testing.T) *utils.KeyManager {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "private.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	keys, err := utils.LoadKeyManager("", file)
+	if err != nil {
+		t.Fatalf("LoadKeyManager error: %v", err)
+	}
+	return keys
+}
+
+func TestIssueTokenChecksCreator(t *testing.T) {
+	keys := newTestKeyManager(t)
+	creatorID := uint(1)
+	secret := "client-secret"
+
+	tests := []struct {
+		name    string
+		creator *models.User
+		scope   string
+		// organizationID organization ของ client (0 = client ที่ไม่มี organization)
+		organizationID uint
+		// member ผู้สร้างยังเป็นสมาชิกของ organization ของ client
+		member  bool
+		want    []string
+		wantOrg uint
+		wantErr error
+	}{
+		{
+			name:    "creator still has every scope",
+			creator: &models.User{ID: 1, Role: "admin", IsActive: true},
+			want:    []string{models.PermUsersRead, models.PermUsersUpdate},
+		},
+		{
+			name:           "token acts in the client's organization",
+			creator:        &models.User{ID: 1, Role: "admin", IsActive: true},
+			organizationID: 3,
+			member:         true,
+			want:           []string{models.PermUsersRead, models.PermUsersUpdate},
+			wantOrg:        3,
+		},
+		{
+			name:           "creator left the client's organization",
+			creator:        &models.User{ID: 1, Role: "admin", IsActive: true},
+			organizationID: 3,
+			want:           []string{models.PermUsersRead, models.PermUsersUpdate},
+		},
+		{
+			name:    "scope dropped from creator's role",
+			creator: &models.User{ID: 1, Role: "support", IsActive: true},
+			want:    []string{models.PermUsersRead},
+		},
+		{
+			name:    "requested scope dropped from creator's role",
+			creator: &models.User{ID: 1, Role: "support", IsActive: true},
+			scope:   models.PermUsersUpdate,
+			wantErr: ErrInvalidClientScope,
+		},
+		{
+			name:    "creator deactivated",
+			creator: &models.User{ID: 1, Role: "admin", IsActive: false},
+			wantErr: ErrInvalidClient,
+		},
+		{
+			name:    "creator deleted",
+			wantErr: ErrInvalidClient,
+		},
+	}
+
+	for _, tt := range tests {
+		users := &fakePasskeyUsers{users: map[uint]*models.User{}}
+		if tt.creator != nil {
+			users.users[tt.creator.ID] = tt.creator
+		}
+		roles := &fakeRoles{permissions: map[string][]string{
+			"admin":   {models.PermUsersRead, models.PermUsersUpdate},
+			"support": {models.PermUsersRead},
+		}}
+		clients := &fakeClients{client: &models.OAuthClient{
+			ID:         1,
+			ClientID:   "client-1",
+			SecretHash: utils.HashToken(secret),
+			Scopes:     models.PermUsersRead + "," + models.PermUsersUpdate,
+			CreatedBy:  &creatorID,
+		}}
+		memberships := &fakeOrgMemberships{}
+		if tt.organizationID != 0 {
+			clients.client.OrganizationID = &tt.organizationID
+		}
+		if tt.member {
+			memberships.list = append(memberships.list, &models.Membership{OrganizationID: tt.organizationID, UserID: creatorID, Role: models.OrgRoleAdmin})
+		}
+		service := NewOAuthClientService(keys, users, roles, memberships, clients, &fakeAudit{})
+
+		token, err := service.IssueToken("client-1", secret, tt.scope)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: IssueToken error: %v", tt.name, err)
+		}
+
+		claims, err := utils.ValidateJWT(token.AccessToken, keys)
+		if err != nil {
+			t.Fatalf("%s: ValidateJWT error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(claims.Permissions, tt.want) {
+			t.Errorf("%s: permissions = %v, want %v", tt.name, claims.Permissions, tt.want)
+		}
+		if claims.OrgID != tt.wantOrg {
+			t.Errorf("%s: org_id = %d, want %d", tt.name, claims.OrgID, tt.wantOrg)
+		}
+	}
+}
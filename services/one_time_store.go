@@ -0,0 +1,62 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// oneTimeStore เก็บค่าชั่วคราวที่มีอายุจำกัดและอ่านได้เพียงครั้งเดียว
+type oneTimeStore[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]oneTimeEntry[T]
+}
+
+type oneTimeEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func newOneTimeStore[T any](ttl time.Duration) *oneTimeStore[T] {
+	return &oneTimeStore[T]{
+		ttl:     ttl,
+		entries: make(map[string]oneTimeEntry[T]),
+	}
+}
+
+// Put บันทึกค่าโดยใช้ key ที่กำหนด พร้อมลบ entry ที่หมดอายุออก
+func (s *oneTimeStore[T]) Put(key string, value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+
+	s.entries[key] = oneTimeEntry[T]{
+		value:     value,
+		expiresAt: now.Add(s.ttl),
+	}
+}
+
+// Take ดึงค่าออกและลบทิ้งทันที คืน false ถ้าไม่มีหรือหมดอายุแล้ว
+func (s *oneTimeStore[T]) Take(key string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	delete(s.entries, key)
+
+	if time.Now().After(entry.expiresAt) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
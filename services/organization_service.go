@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+)
+
+var (
+	// ErrNoActiveOrganization token ไม่มี organization ที่ใช้งานอยู่ (user ยังไม่ได้เป็นสมาชิกของ organization ใด)
+	ErrNoActiveOrganization = errors.New("no active organization, switch to an organization first")
+	// ErrNotOrganizationMember user ไม่ได้เป็นสมาชิกของ organization
+	ErrNotOrganizationMember = errors.New("you are not a member of this organization")
+	// ErrOrganizationForbidden เฉพาะ owner และ admin ของ organization จัดการสมาชิกได้
+	ErrOrganizationForbidden = errors.New("only owners and admins of the organization can manage members")
+	// ErrOwnerRoleRequired เฉพาะ owner ให้ เปลี่ยน หรือนำ role owner ออกได้
+	ErrOwnerRoleRequired = errors.New("only owners can grant, change or remove the owner role")
+	// ErrLastOwner organization ต้องมี owner อย่างน้อยหนึ่งคน
+	ErrLastOwner = errors.New("cannot remove or demote the last owner of the organization")
+	// ErrInvalidOrgRole role ของ organization ไม่ถูกต้อง
+	ErrInvalidOrgRole = errors.New("role must be owner, admin or member")
+	// ErrInvalidOrganization ชื่อหรือ slug ของ organization ไม่ถูกต้อง
+	ErrInvalidOrganization = errors.New("organization name is required and slug must be 3-63 lowercase letters, digits or hyphens")
+	// ErrOrganizationExists slug ถูกใช้แล้ว
+	ErrOrganizationExists = errors.New("organization slug is already taken")
+	// ErrAlreadyMember user เป็นสมาชิกของ organization อยู่แล้ว
+	ErrAlreadyMember = errors.New("user is already a member of this organization")
+)
+
+const maxOrganizationNameLength = 128
+
+var (
+	organizationSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+	slugSeparatorPattern    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// OrganizationService interface สำหรับ organizations และสมาชิก
+type OrganizationService interface {
+	CreateOrganization(actorID uint, name, slug string) (*models.Organization, error)
+	ListMemberships(userID uint) ([]*models.Membership, error)
+	ListMembers(actorID, organizationID uint) ([]*models.Membership, error)
+	AddMember(actorID, organizationID uint, email, role string) (*models.Membership, error)
+	UpdateMemberRole(actorID, organizationID, userID uint, role string) error
+	RemoveMember(actorID, organizationID, userID uint) error
+}
+
+// organizationService struct implements OrganizationService interface
+type organizationService struct {
+	userRepo         repositories.UserRepository
+	organizationRepo repositories.OrganizationRepository
+	membershipRepo   repositories.MembershipRepository
+	revocations      TokenRevocationService
+	audit            AuditService
+}
+
+// NewOrganizationService creates new organization service instance
+func NewOrganizationService(userRepo repositories.UserRepository, organizationRepo repositories.OrganizationRepository, membershipRepo repositories.MembershipRepository, revocations TokenRevocationService, audit AuditService) OrganizationService {
+	return &organizationService{
+		userRepo:         userRepo,
+		organizationRepo: organizationRepo,
+		membershipRepo:   membershipRepo,
+		revocations:      revocations,
+		audit:            audit,
+	}
+}
+
+// defaultOrganizationID organization แรกที่ user เป็นสมาชิก (nil ถ้าไม่ได้เป็นสมาชิกของ organization ใด)
+func defaultOrganizationID(membershipRepo repositories.MembershipRepository, userID uint) (*uint, error) {
+	membership, err := membershipRepo.GetDefault(userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &membership.OrganizationID, nil
+}
+
+// CreateOrganization สร้าง organization ใหม่ ผู้สร้างเป็น owner (ไม่ระบุ slug จะสร้างจากชื่อ)
+func (s *organizationService) CreateOrganization(actorID uint, name, slug string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		slug = strings.Trim(slugSeparatorPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	}
+	if name == "" || len(name) > maxOrganizationNameLength || !organizationSlugPattern.MatchString(slug) {
+		return nil, ErrInvalidOrganization
+	}
+
+	if _, err := s.organizationRepo.GetBySlug(slug); err == nil {
+		return nil, ErrOrganizationExists
+	} else if !errors.Is(err, repositories.ErrOrganizationNotFound) {
+		return nil, err
+	}
+
+	organization := &models.Organization{Name: name, Slug: slug}
+	if err := s.organizationRepo.CreateWithOwner(organization, actorID); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditOrganizationCreated,
+		ActorID: &actorID,
+		UserID:  &actorID,
+		Details: fmt.Sprintf("organization %d (%s)", organization.ID, organization.Slug),
+	})
+	return organization, nil
+}
+
+// ListMemberships ดึง organizations ที่ user เป็นสมาชิกพร้อม role
+func (s *organizationService) ListMemberships(userID uint) ([]*models.Membership, error) {
+	return s.membershipRepo.GetByUserID(userID)
+}
+
+// organizationMember membership ของผู้เรียกใน organization
+func organizationMember(membershipRepo repositories.MembershipRepository, actorID, organizationID uint) (*models.Membership, error) {
+	membership, err := membershipRepo.Get(organizationID, actorID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, ErrNotOrganizationMember
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// memberManager ผู้เรียกต้องเป็น owner หรือ admin ของ organization และให้ role owner ได้เฉพาะ owner
+func memberManager(membershipRepo repositories.MembershipRepository, actorID, organizationID uint, grantRole string) (*models.Membership, error) {
+	actor, err := organizationMember(membershipRepo, actorID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanManageMembers(actor.Role) {
+		return nil, ErrOrganizationForbidden
+	}
+	if grantRole == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner {
+		return nil, ErrOwnerRoleRequired
+	}
+	return actor, nil
+}
+
+// ListMembers ดึงสมาชิกของ organization (เฉพาะสมาชิกของ organization นั้น)
+func (s *organizationService) ListMembers(actorID, organizationID uint) ([]*models.Membership, error) {
+	if _, err := organizationMember(s.membershipRepo, actorID, organizationID); err != nil {
+		return nil, err
+	}
+	return s.membershipRepo.GetByOrganizationID(organizationID)
+}
+
+// AddMember เพิ่ม user ที่มีบัญชีอยู่แล้วเข้า organization (role ว่างเป็น member)
+func (s *organizationService) AddMember(actorID, organizationID uint, email, role string) (*models.Membership, error) {
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	if !models.ValidOrgRole(role) {
+		return nil, ErrInvalidOrgRole
+	}
+
+	if _, err := memberManager(s.membershipRepo, actorID, organizationID, role); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if _, err := s.membershipRepo.Get(organizationID, user.ID); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, repositories.ErrMembershipNotFound) {
+		return nil, err
+	}
+
+	membership := &models.Membership{
+		OrganizationID: organizationID,
+		UserID:         user.ID,
+		Role:           role,
+	}
+	if err := s.membershipRepo.Create(membership); err != nil {
+		return nil, err
+	}
+	membership.User = user
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMemberAdded,
+		ActorID: &actorID,
+		UserID:  &user.ID,
+		Details: fmt.Sprintf("organization %d as %s", organizationID, role),
+	})
+	return membership, nil
+}
+
+// authorizeMemberChange ตรวจสอบสิทธิ์เปลี่ยน role หรือนำสมาชิกออก และกันไม่ให้ organization ไม่มี owner
+func (s *organizationService) authorizeMemberChange(actorID, organizationID, userID uint, newRole string) (*models.Membership, error) {
+	actor, err := organizationMember(s.membershipRepo, actorID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.membershipRepo.Get(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// สมาชิกออกจาก organization เองได้ทุก role
+	leaving := actorID == userID && newRole == ""
+	if !leaving && !models.CanManageMembers(actor.Role) {
+		return nil, ErrOrganizationForbidden
+	}
+	if (target.Role == models.OrgRoleOwner || newRole == models.OrgRoleOwner) && !leaving && actor.Role != models.OrgRoleOwner {
+		return nil, ErrOwnerRoleRequired
+	}
+
+	if target.Role == models.OrgRoleOwner && newRole != models.OrgRoleOwner {
+		owners, err := s.membershipRepo.CountByRole(organizationID, models.OrgRoleOwner)
+		if err != nil {
+			return nil, err
+		}
+		if owners <= 1 {
+			return nil, ErrLastOwner
+		}
+	}
+	return target, nil
+}
+
+// UpdateMemberRole เปลี่ยน role ของสมาชิก (owner เท่านั้นที่ให้หรือเปลี่ยน role owner ได้)
+// และเพิกถอน token เดิมของสมาชิกที่ออกตอนยังมี role เดิม
+func (s *organizationService) UpdateMemberRole(actorID, organizationID, userID uint, role string) error {
+	if !models.ValidOrgRole(role) {
+		return ErrInvalidOrgRole
+	}
+
+	target, err := s.authorizeMemberChange(actorID, organizationID, userID, role)
+	if err != nil {
+		return err
+	}
+	if target.Role == role {
+		return nil
+	}
+	if err := s.membershipRepo.UpdateRole(organizationID, userID, role); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeAllForUser(userID); err != nil {
+		return fmt.Errorf("failed to revoke member tokens: %w", err)
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMemberRoleChanged,
+		ActorID: &actorID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("organization %d: %s -> %s", organizationID, target.Role, role),
+	})
+	return nil
+}
+
+// RemoveMember นำสมาชิกออกจาก organization หรือออกจาก organization เอง
+// และเพิกถอน token เดิมของสมาชิกที่ยังมี org_id ของ organization นี้
+func (s *organizationService) RemoveMember(actorID, organizationID, userID uint) error {
+	target, err := s.authorizeMemberChange(actorID, organizationID, userID, "")
+	if err != nil {
+		return err
+	}
+	if err := s.membershipRepo.Delete(organizationID, userID); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeAllForUser(userID); err != nil {
+		return fmt.Errorf("failed to revoke member tokens: %w", err)
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMemberRemoved,
+		ActorID: &actorID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("organization %d (was %s)", organizationID, target.Role),
+	})
+	return nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"collp-backend/models"
+)
+
+func TestMemberChangesRevokeTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(service OrganizationService) error
+	}{
+		{
+			name:   "role changed",
+			change: func(service OrganizationService) error { return service.UpdateMemberRole(1, 3, 7, models.OrgRoleAdmin) },
+		},
+		{
+			name:   "removed by an owner",
+			change: func(service OrganizationService) error { return service.RemoveMember(1, 3, 7) },
+		},
+		{
+			name:   "left the organization",
+			change: func(service OrganizationService) error { return service.RemoveMember(7, 3, 7) },
+		},
+	}
+
+	for _, tt := range tests {
+		memberships := &fakeOrgMemberships{list: []*models.Membership{
+			{OrganizationID: 3, UserID: 1, Role: models.OrgRoleOwner},
+			{OrganizationID: 3, UserID: 7, Role: models.OrgRoleMember},
+		}}
+		revocations := &fakeUserRevocations{}
+		service := NewOrganizationService(nil, nil, memberships, revocations, &fakeAudit{})
+
+		if err := tt.change(service); err != nil {
+			t.Fatalf("%s: error: %v", tt.name, err)
+		}
+		if len(revocations.revoked) != 1 || revocations.revoked[0] != 7 {
+			t.Errorf("%s: revoked = %v, want [7]", tt.name, revocations.revoked)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// activeOrganization organization ของ session ถ้า user ยังเป็นสมาชิกอยู่
+// ถ้าถูกนำออกจาก organization แล้วจะเปลี่ยน session ไปใช้ organization เริ่มต้นของ user (0 = ไม่มี)
+func (s *AuthService) activeOrganization(userID uint, session *models.Session) (uint, error) {
+	if session.OrganizationID != nil {
+		_, err := s.memberships.Get(*session.OrganizationID, userID)
+		if err == nil {
+			return *session.OrganizationID, nil
+		}
+		if !errors.Is(err, repositories.ErrMembershipNotFound) {
+			return 0, err
+		}
+	}
+
+	organizationID, err := defaultOrganizationID(s.memberships, userID)
+	if err != nil {
+		return 0, err
+	}
+	if organizationID == nil && session.OrganizationID == nil {
+		return 0, nil
+	}
+	if err := s.sessionRepo.SetOrganization(session.ID, organizationID); err != nil {
+		return 0, err
+	}
+	session.OrganizationID = organizationID
+	if organizationID == nil {
+		return 0, nil
+	}
+	return *organizationID, nil
+}
+
+// SwitchOrganization เปลี่ยน organization ที่ใช้งานของ session ปัจจุบันแล้วออก token คู่ใหม่ที่มี org_id ใหม่
+// access token และ refresh token เดิมของ session ใช้ไม่ได้อีก
+func (s *AuthService) SwitchOrganization(claims *utils.JWTClaims, organizationID uint) (*AuthResult, error) {
+	if claims.SessionID == 0 {
+		return nil, repositories.ErrSessionNotFound
+	}
+	if _, err := s.memberships.Get(organizationID, claims.UserID); err != nil {
+		if errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, ErrNotOrganizationMember
+		}
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.GetActiveByID(claims.UserID, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	if err := s.sessionRepo.SetOrganization(session.ID, &organizationID); err != nil {
+		return nil, err
+	}
+	session.OrganizationID = &organizationID
+
+	// หมุน token ของ session: refresh token เดิมถือว่าใช้แล้ว และเพิกถอน access token ที่มี org_id เดิม
+	if err := s.refreshRepo.MarkFamilyUsed(session.FamilyID); err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt != nil {
+		if err := s.revocations.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.issueTokenForSession(user, session)
+}
@@ -0,0 +1,17 @@
+package services
+
+// BeginPasskeyLogin เริ่ม login ด้วย passkey
+func (s *AuthService) BeginPasskeyLogin() (*PasskeyChallenge, error) {
+	return s.passkeys.BeginLogin()
+}
+
+// LoginWithPasskey ตรวจสอบ passkey แล้วออก JWT
+// passkey บังคับ user verification (PIN/biometric บนอุปกรณ์) จึงนับเป็น 2FA ในตัว ไม่ต้องถาม TOTP ซ้ำ
+func (s *AuthService) LoginWithPasskey(sessionToken string, credential []byte, client ClientInfo) (*AuthResult, error) {
+	user, err := s.passkeys.FinishLogin(sessionToken, credential, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueToken(user, client)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"collp-backend/mailer"
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+var (
+	// ErrInvalidResetToken token reset password ไม่ถูกต้อง หมดอายุ หรือถูกใช้ไปแล้ว
+	ErrInvalidResetToken = errors.New("invalid, expired or already used password reset token")
+	// ErrWeakPassword password ใหม่ไม่ผ่านเงื่อนไข utils.IsValidPassword
+	ErrWeakPassword = errors.New("password must be at least 8 characters long and contain uppercase, lowercase, and number")
+)
+
+// PasswordResetTokenTTL อายุของลิงก์ reset password
+const PasswordResetTokenTTL = time.Hour
+
+// PasswordResetService interface สำหรับลืม password และตั้ง password ใหม่ด้วยลิงก์ทาง email
+type PasswordResetService interface {
+	RequestReset(email string) error
+	ResetPassword(token, newPassword, ip string) error
+}
+
+// passwordResetService struct implements PasswordResetService interface
+type passwordResetService struct {
+	userRepo    repositories.UserRepository
+	tokenRepo   repositories.UserTokenRepository
+	patRepo     repositories.PersonalAccessTokenRepository
+	clientRepo  repositories.OAuthClientRepository
+	revocations TokenRevocationService
+	audit       AuditService
+	mail        mailer.Mailer
+	// resetURL หน้า frontend ที่รับ ?token= แล้วเรียก POST /api/auth/password/reset
+	resetURL string
+}
+
+// NewPasswordResetService creates new password reset service instance
+func NewPasswordResetService(userRepo repositories.UserRepository, tokenRepo repositories.UserTokenRepository, patRepo repositories.PersonalAccessTokenRepository, clientRepo repositories.OAuthClientRepository, revocations TokenRevocationService, audit AuditService, mail mailer.Mailer, resetURL string) PasswordResetService {
+	return &passwordResetService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		patRepo:     patRepo,
+		clientRepo:  clientRepo,
+		revocations: revocations,
+		audit:       audit,
+		mail:        mail,
+		resetURL:    resetURL,
+	}
+}
+
+// RequestReset ส่งลิงก์ reset password ถ้ามีบัญชีที่ใช้งานได้
+// คืน nil เสมอเมื่อไม่พบ email เพื่อไม่ให้ใช้ตรวจว่ามีบัญชีหรือไม่
+func (s *passwordResetService) RequestReset(email string) error {
+	user, err := s.userRepo.GetByEmail(strings.ToLower(utils.SanitizeString(email)))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	// สร้างลิงก์และส่งแบบ async เพื่อให้เวลาตอบกลับไม่ต่างจากกรณีไม่พบ email
+	go func() {
+		if err := s.sendResetLink(user); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// sendResetLink ออก token reset password ใหม่แล้วส่งลิงก์ไปที่ email ของ user
+func (s *passwordResetService) sendResetLink(user *models.User) error {
+	// ลิงก์ใหม่ทำให้ลิงก์เก่าที่ยังไม่ใช้ใช้ไม่ได้
+	if err := s.tokenRepo.InvalidateForUser(user.ID, models.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token := utils.GenerateRandomString(43)
+	if err := s.tokenRepo.Create(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposePasswordReset,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(PasswordResetTokenTTL),
+	}); err != nil {
+		return err
+	}
+
+	link, err := withTokenParam(s.resetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.mail.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your CollP password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone (hopefully you) asked to reset the password of your CollP account. Open the link below to choose a new password:\n\n%s\n\nThe link expires in %d minutes and can be used once. If you did not request this, you can ignore this email.\n",
+			user.Name, link, int(PasswordResetTokenTTL.Minutes())),
+	})
+}
+
+// ResetPassword ตั้ง password ใหม่ด้วย token (ใช้ได้ครั้งเดียว) แล้วเพิกถอน session และ personal access tokens
+// ทั้งหมดของ user และล้าง secret ของ OAuth clients ที่ user สร้าง
+func (s *passwordResetService) ResetPassword(token, newPassword, ip string) error {
+	if !utils.IsValidPassword(newPassword) {
+		return ErrWeakPassword
+	}
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+
+	stored, err := s.tokenRepo.Consume(models.TokenPurposePasswordReset, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return ErrInvalidResetToken
+	}
+
+	passwordHash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// ลิงก์ที่มาถึง email แล้วถือว่ายืนยัน email ด้วย
+	fields := map[string]interface{}{"password_hash": passwordHash}
+	if user.EmailVerifiedAt == nil {
+		fields["email_verified_at"] = time.Now()
+	}
+	if err := s.userRepo.UpdateFields(user.ID, fields); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// password ใหม่ปลดล็อคบัญชีที่ถูกล็อคจากการเดา password
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		return err
+	}
+
+	// ออกจากระบบทุกอุปกรณ์: refresh tokens และ access tokens ที่ออกก่อนหน้านี้ใช้ไม่ได้
+	if err := s.revocations.RevokeAllForUser(user.ID); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	// personal access tokens และ OAuth clients ไม่ผ่าน JWT revocation ผู้ที่เข้าถึงบัญชีก่อน reset อาจสร้างไว้
+	revokedTokens, err := s.patRepo.RevokeAllForUser(user.ID)
+	if err != nil {
+		return err
+	}
+	expiredClients, err := s.clientRepo.ExpireSecretsByCreator(user.ID)
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditPasswordReset,
+		UserID:  &user.ID,
+		IP:      ip,
+		Details: fmt.Sprintf("revoked all sessions, %d personal access tokens and the secrets of %d OAuth clients", revokedTokens, expiredClients),
+	})
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// fakeResetUsers user repository ของ user ที่ reset password
+type fakeResetUsers struct {
+	fakePasskeyUsers
+	updated map[string]interface{}
+}
+
+func (r *fakeResetUsers) UpdateFields(id uint, fields map[string]interface{}) error {
+	r.updated = fields
+	return nil
+}
+
+func (r *fakeResetUsers) ResetFailedLogins(id uint) error {
+	return nil
+}
+
+// fakeUserTokens user token repository ที่มี token เดียว
+type fakeUserTokens struct {
+	repositories.UserTokenRepository
+	token *models.UserToken
+}
+
+func (r *fakeUserTokens) Create(token *models.UserToken) error {
+	r.token = token
+	return nil
+}
+
+func (r *fakeUserTokens) InvalidateForUser(userID uint, purpose string) error {
+	r.token = nil
+	return nil
+}
+
+func (r *fakeUserTokens) Consume(purpose, tokenHash string) (*models.UserToken, error) {
+	if r.token == nil || r.token.Purpose != purpose || r.token.TokenHash != tokenHash {
+		return nil, repositories.ErrUserTokenNotFound
+	}
+	token := r.token
+	r.token = nil
+	return token, nil
+}
+
+// fakePersonalAccessTokens บันทึก user ที่ถูกเพิกถอน personal access tokens ทั้งหมด
+type fakePersonalAccessTokens struct {
+	repositories.PersonalAccessTokenRepository
+	revoked []uint
+}
+
+func (r *fakePersonalAccessTokens) RevokeAllForUser(userID uint) (int64, error) {
+	r.revoked = append(r.revoked, userID)
+	return 2, nil
+}
+
+// fakeCreatorClients บันทึก user ที่ clients ถูกล้าง secret
+type fakeCreatorClients struct {
+	repositories.OAuthClientRepository
+	expired []uint
+}
+
+func (r *fakeCreatorClients) ExpireSecretsByCreator(userID uint) (int64, error) {
+	r.expired = append(r.expired, userID)
+	return 1, nil
+}
+
+func TestResetPasswordRevokesAllCredentials(t *testing.T) {
+	users := &fakeResetUsers{fakePasskeyUsers: fakePasskeyUsers{users: map[uint]*models.User{
+		7: {ID: 7, Email: "user@example.com", IsActive: true},
+	}}}
+	tokens := &fakeUserTokens{token: &models.UserToken{UserID: 7, Purpose: models.TokenPurposePasswordReset, TokenHash: utils.HashToken("reset-token")}}
+	pats := &fakePersonalAccessTokens{}
+	clients := &fakeCreatorClients{}
+	revocations := &fakeUserRevocations{}
+	audit := &fakeAudit{}
+	service := NewPasswordResetService(users, tokens, pats, clients, revocations, audit, &fakeMailer{}, "https://app.example.com/reset")
+
+	if err := service.ResetPassword("reset-token", "NewPassw0rd", "192.0.2.1"); err != nil {
+		t.Fatalf("ResetPassword error: %v", err)
+	}
+	if users.updated["password_hash"] == nil {
+		t.Error("password not updated")
+	}
+
+	revoked := map[string][]uint{
+		"sessions":               revocations.revoked,
+		"personal access tokens": pats.revoked,
+		"OAuth client secrets":   clients.expired,
+	}
+	for name, got := range revoked {
+		if len(got) != 1 || got[0] != 7 {
+			t.Errorf("%s revoked for %v, want [7]", name, got)
+		}
+	}
+	if len(audit.actions) != 1 || audit.actions[0] != models.AuditPasswordReset {
+		t.Errorf("audit actions = %v, want [%s]", audit.actions, models.AuditPasswordReset)
+	}
+
+	// token ใช้ได้ครั้งเดียว
+	if err := service.ResetPassword("reset-token", "NewPassw0rd", "192.0.2.1"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("reused token: error = %v, want %v", err, ErrInvalidResetToken)
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidTokenName ชื่อ token ว่างหรือยาวเกินไป
+	ErrInvalidTokenName = errors.New("token name is required and must be at most 64 characters")
+	// ErrInvalidTokenScope scope ที่ขอไม่อยู่ใน permissions ของ role ปัจจุบัน (ใช้กับ OAuth clients ด้วย)
+	ErrInvalidTokenScope = errors.New("scopes must be permissions of your role")
+	// ErrInvalidTokenExpiry อายุ token ต้องอยู่ระหว่าง 1 ถึง 365 วัน
+	ErrInvalidTokenExpiry = errors.New("token expiry must be between 1 and 365 days")
+)
+
+const (
+	// PersonalAccessTokenDefaultDays อายุ token เมื่อไม่ได้ระบุ
+	PersonalAccessTokenDefaultDays = 30
+	// PersonalAccessTokenMaxDays อายุสูงสุดของ token
+	PersonalAccessTokenMaxDays = 365
+	maxTokenNameLength         = 64
+	// tokenUseRecordInterval บันทึก last_used ไม่บ่อยกว่านี้ถ้า IP ไม่เปลี่ยน (ไม่ต้องเขียน DB ทุก request)
+	tokenUseRecordInterval = time.Minute
+)
+
+// CreatedPersonalAccessToken token ที่สร้างใหม่ Token แสดงได้ครั้งเดียวเพราะเก็บเฉพาะ hash
+type CreatedPersonalAccessToken struct {
+	Token string `json:"token"`
+	*models.PersonalAccessToken
+}
+
+// PersonalAccessTokenService interface สำหรับ personal access tokens ที่ใช้แทน JWT ใน scripts และ CI
+type PersonalAccessTokenService interface {
+	CreateToken(userID, organizationID uint, name string, scopes []string, expiresInDays int) (*CreatedPersonalAccessToken, error)
+	ListTokens(userID uint) ([]*models.PersonalAccessToken, error)
+	RevokeToken(userID, id uint) error
+	// AuthenticatePersonalAccessToken คืน claims ของ token ที่ใช้ได้ หรือ nil ถ้า token ไม่ถูกต้อง
+	AuthenticatePersonalAccessToken(token, ip string) (*utils.JWTClaims, error)
+}
+
+// personalAccessTokenService struct implements PersonalAccessTokenService interface
+type personalAccessTokenService struct {
+	userRepo       repositories.UserRepository
+	roleRepo       repositories.RoleRepository
+	tokenRepo      repositories.PersonalAccessTokenRepository
+	membershipRepo repositories.MembershipRepository
+	audit          AuditService
+}
+
+// NewPersonalAccessTokenService creates new personal access token service instance
+func NewPersonalAccessTokenService(userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, tokenRepo repositories.PersonalAccessTokenRepository, membershipRepo repositories.MembershipRepository, audit AuditService) PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		tokenRepo:      tokenRepo,
+		membershipRepo: membershipRepo,
+		audit:          audit,
+	}
+}
+
+// rolePermissions permissions ปัจจุบันของ role (role ที่ถูกลบแล้วไม่มี permission)
+func rolePermissions(roleRepo repositories.RoleRepository, role string) ([]string, error) {
+	permissions, err := roleRepo.GetPermissionNames(role)
+	if err != nil {
+		if errors.Is(err, repositories.ErrRoleNotFound) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// CreateToken สร้าง token ใหม่ที่มี scopes ไม่เกิน permissions ของ role ปัจจุบัน
+// token ทำงานใน organization ที่ใช้งานอยู่ตอนสร้าง (0 = ไม่มี)
+func (s *personalAccessTokenService) CreateToken(userID, organizationID uint, name string, scopes []string, expiresInDays int) (*CreatedPersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTokenNameLength {
+		return nil, ErrInvalidTokenName
+	}
+	if expiresInDays == 0 {
+		expiresInDays = PersonalAccessTokenDefaultDays
+	}
+	if expiresInDays < 1 || expiresInDays > PersonalAccessTokenMaxDays {
+		return nil, ErrInvalidTokenExpiry
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	permissions, err := rolePermissions(s.roleRepo, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	granted := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !containsString(permissions, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTokenScope, scope)
+		}
+		if !containsString(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	token := models.PersonalAccessTokenPrefix + utils.GenerateRandomString(40)
+	stored := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(models.PersonalAccessTokenPrefix)+8],
+		TokenHash: utils.HashToken(token),
+		Scopes:    strings.Join(granted, ","),
+		ExpiresAt: time.Now().AddDate(0, 0, expiresInDays),
+	}
+	if organizationID != 0 {
+		stored.OrganizationID = &organizationID
+	}
+	if err := s.tokenRepo.Create(stored); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditPATCreated,
+		ActorID: &userID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("token %d (%s) scopes [%s]", stored.ID, stored.Prefix, stored.Scopes),
+	})
+	return &CreatedPersonalAccessToken{Token: token, PersonalAccessToken: stored}, nil
+}
+
+// ListTokens ดึง tokens ที่ยังไม่ถูกเพิกถอนของ user
+func (s *personalAccessTokenService) ListTokens(userID uint) ([]*models.PersonalAccessToken, error) {
+	return s.tokenRepo.GetByUserID(userID)
+}
+
+// RevokeToken เพิกถอน token ของ user
+func (s *personalAccessTokenService) RevokeToken(userID, id uint) error {
+	if err := s.tokenRepo.Revoke(userID, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditPATRevoked,
+		ActorID: &userID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("token %d", id),
+	})
+	return nil
+}
+
+// AuthenticatePersonalAccessToken ตรวจสอบ token สำหรับ AuthMiddleware
+// permissions คือ scopes ที่ยังอยู่ใน role ปัจจุบันของ user จึงลดลงตามเมื่อ role เปลี่ยน
+func (s *personalAccessTokenService) AuthenticatePersonalAccessToken(token, ip string) (*utils.JWTClaims, error) {
+	stored, err := s.tokenRepo.GetByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrPersonalAccessTokenNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !now.Before(stored.ExpiresAt) {
+		return nil, nil
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, nil
+	}
+
+	permissions, err := rolePermissions(s.roleRepo, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	granted := []string{}
+	for _, scope := range strings.Split(stored.Scopes, ",") {
+		if containsString(permissions, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	// organization ของ token ใช้ได้เท่าที่ user ยังเป็นสมาชิกอยู่
+	var organizationID uint
+	if stored.OrganizationID != nil {
+		if _, err := s.membershipRepo.Get(*stored.OrganizationID, user.ID); err == nil {
+			organizationID = *stored.OrganizationID
+		} else if !errors.Is(err, repositories.ErrMembershipNotFound) {
+			return nil, err
+		}
+	}
+
+	if stored.LastUsedAt == nil || stored.LastUsedIP != ip || now.Sub(*stored.LastUsedAt) >= tokenUseRecordInterval {
+		if err := s.tokenRepo.RecordUse(stored.ID, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	return &utils.JWTClaims{
+		UserID:        user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+		Permissions:   granted,
+		TokenType:     utils.TokenTypePersonalAccess,
+		OrgID:         organizationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        fmt.Sprintf("pat_%d", stored.ID),
+			Subject:   user.Email,
+			IssuedAt:  jwt.NewNumericDate(stored.CreatedAt),
+			ExpiresAt: jwt.NewNumericDate(stored.ExpiresAt),
+		},
+	}, nil
+}
+
+// containsString ตรวจสอบว่ามี value อยู่ใน values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
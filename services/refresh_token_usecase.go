@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// RefreshTokens หมุน refresh token: ใช้ token เดิมได้ครั้งเดียวแล้วออกคู่ใหม่ใน family เดิม
+// ถ้า token ที่ใช้ไปแล้วถูกส่งมาอีก จะเพิกถอนทั้ง family ทันที
+func (s *AuthService) RefreshTokens(refreshToken string, client ClientInfo) (*AuthResult, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.refreshRepo.GetByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	marked := false
+	if stored.UsedAt == nil {
+		if marked, err = s.refreshRepo.MarkUsed(stored.ID); err != nil {
+			return nil, err
+		}
+	}
+	if !marked {
+		log.Printf("Refresh token reuse detected for user %d, revoking family %s", stored.UserID, stored.FamilyID)
+		if err := s.refreshRepo.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReuse
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsActive {
+		if err := s.refreshRepo.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrUserInactive
+	}
+
+	// session ของ family เดิม (family ที่ออกก่อนมี sessions จะได้ session ใหม่)
+	session, err := s.sessionRepo.GetByFamilyID(stored.FamilyID)
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		session = newSession(user.ID, stored.FamilyID, client)
+		err = s.sessionRepo.Create(session)
+	} else if err == nil {
+		err = s.sessionRepo.Touch(session.ID, client.IP, truncateUserAgent(client.UserAgent))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokenForSession(user, session)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// maxUserAgentLength ตัด user agent ที่ยาวผิดปกติก่อนบันทึก
+const maxUserAgentLength = 512
+
+// SessionActivityInterval ระยะห่างขั้นต่ำระหว่างการอัพเดท last_seen_at ของ session เดียวกัน
+const SessionActivityInterval = time.Minute
+
+// ClientInfo ข้อมูลอุปกรณ์ของ request ที่ login หรือ refresh
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// newSession สร้าง session ของ refresh token family ใหม่
+func newSession(userID uint, familyID string, client ClientInfo) *models.Session {
+	return &models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  truncateUserAgent(client.UserAgent),
+		IP:         client.IP,
+		LastSeenAt: time.Now(),
+	}
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+// SessionService interface สำหรับดูและปิด sessions (อุปกรณ์ที่ login อยู่)
+type SessionService interface {
+	ListSessions(userID, currentSessionID uint) ([]*models.Session, error)
+	RevokeSession(actorID, userID, id uint) error
+}
+
+// sessionService struct implements SessionService interface
+type sessionService struct {
+	sessionRepo repositories.SessionRepository
+	refreshRepo repositories.RefreshTokenRepository
+	revocations TokenRevocationService
+	audit       AuditService
+}
+
+// NewSessionService creates new session service instance
+func NewSessionService(sessionRepo repositories.SessionRepository, refreshRepo repositories.RefreshTokenRepository, revocations TokenRevocationService, audit AuditService) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		refreshRepo: refreshRepo,
+		revocations: revocations,
+		audit:       audit,
+	}
+}
+
+// ListSessions ดึง sessions ที่ยังใช้งานได้ของ user และระบุ session ของ token ปัจจุบัน
+func (s *sessionService) ListSessions(userID, currentSessionID uint) ([]*models.Session, error) {
+	sessions, err := s.sessionRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = currentSessionID != 0 && session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ปิด session: เพิกถอน refresh token family และ access tokens ที่ยังไม่หมดอายุของ session นั้น
+func (s *sessionService) RevokeSession(actorID, userID, id uint) error {
+	session, err := s.sessionRepo.GetActiveByID(userID, id)
+	if err != nil {
+		return err
+	}
+
+	// access token คู่กับ refresh token ที่ออกภายใน AccessTokenTTL อาจยังใช้ได้อยู่
+	tokens, err := s.refreshRepo.GetIssuedSince(session.FamilyID, time.Now().Add(-utils.AccessTokenTTL))
+	if err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeFamily(session.FamilyID); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if token.AccessTokenID == "" {
+			continue
+		}
+		if err := s.revocations.RevokeToken(token.AccessTokenID, userID, token.CreatedAt.Add(utils.AccessTokenTTL)); err != nil {
+			return err
+		}
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditSessionRevoked,
+		ActorID: &actorID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("session %d (%s, %s)", session.ID, session.IP, session.UserAgent),
+	})
+	return nil
+}
+
+// SessionActivityRecorder อัพเดท last_seen_at, IP และ user agent ของ session จาก request ที่ใช้ access token
+type SessionActivityRecorder interface {
+	RecordActivity(sessionID uint, ip, userAgent string)
+}
+
+// sessionActivityRecorder struct implements SessionActivityRecorder interface
+// จำเวลาที่อัพเดทแต่ละ session ล่าสุดไว้ในหน่วยความจำ เพื่อเขียนฐานข้อมูลไม่เกินครั้งละ SessionActivityInterval
+type sessionActivityRecorder struct {
+	sessionRepo repositories.SessionRepository
+
+	mu          sync.Mutex
+	lastTouched map[uint]time.Time
+	lastPruned  time.Time
+}
+
+// NewSessionActivityRecorder creates new session activity recorder instance
+func NewSessionActivityRecorder(sessionRepo repositories.SessionRepository) SessionActivityRecorder {
+	return &sessionActivityRecorder{
+		sessionRepo: sessionRepo,
+		lastTouched: make(map[uint]time.Time),
+		lastPruned:  time.Now(),
+	}
+}
+
+// RecordActivity อัพเดท session ถ้ายังไม่ได้อัพเดทภายใน SessionActivityInterval
+// error ของฐานข้อมูลแค่ log ไว้ ไม่ทำให้ request ล้มเหลว
+func (s *sessionActivityRecorder) RecordActivity(sessionID uint, ip, userAgent string) {
+	if !s.due(sessionID, time.Now()) {
+		return
+	}
+	if err := s.sessionRepo.TouchIfIdle(sessionID, ip, truncateUserAgent(userAgent), SessionActivityInterval); err != nil {
+		log.Printf("Failed to record activity of session %d: %v", sessionID, err)
+	}
+}
+
+// due จองการอัพเดท session ถ้าครบ SessionActivityInterval แล้ว และลบ sessions ที่ไม่ได้ใช้ออกจาก map
+func (s *sessionActivityRecorder) due(sessionID uint, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastPruned) >= SessionActivityInterval {
+		for id, touched := range s.lastTouched {
+			if now.Sub(touched) >= SessionActivityInterval {
+				delete(s.lastTouched, id)
+			}
+		}
+		s.lastPruned = now
+	}
+
+	if touched, ok := s.lastTouched[sessionID]; ok && now.Sub(touched) < SessionActivityInterval {
+		return false
+	}
+	s.lastTouched[sessionID] = now
+	return true
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"collp-backend/repositories"
+)
+
+// fakeSessions นับการอัพเดท session แต่ละ id
+type fakeSessions struct {
+	repositories.SessionRepository
+	touched map[uint]int
+}
+
+func (r *fakeSessions) TouchIfIdle(id uint, ip, userAgent string, idle time.Duration) error {
+	r.touched[id]++
+	return nil
+}
+
+func TestRecordActivityIsThrottledPerSession(t *testing.T) {
+	sessions := &fakeSessions{touched: make(map[uint]int)}
+	recorder := NewSessionActivityRecorder(sessions).(*sessionActivityRecorder)
+
+	recorder.RecordActivity(1, "203.0.113.1", "test")
+	recorder.RecordActivity(1, "203.0.113.1", "test")
+	recorder.RecordActivity(2, "203.0.113.2", "test")
+	if sessions.touched[1] != 1 || sessions.touched[2] != 1 {
+		t.Fatalf("touched = %v, want one update per session", sessions.touched)
+	}
+
+	// ครบ SessionActivityInterval แล้วต้องอัพเดทได้อีกครั้ง และ session ที่ไม่ได้ใช้ถูกลบออกจาก map
+	recorder.lastTouched[1] = time.Now().Add(-SessionActivityInterval)
+	recorder.lastTouched[2] = time.Now().Add(-2 * SessionActivityInterval)
+	recorder.lastPruned = time.Now().Add(-SessionActivityInterval)
+	recorder.RecordActivity(1, "203.0.113.1", "test")
+	if sessions.touched[1] != 2 {
+		t.Errorf("touched[1] = %d, want 2", sessions.touched[1])
+	}
+	if _, ok := recorder.lastTouched[2]; ok {
+		t.Error("idle session 2 was not pruned")
+	}
+}
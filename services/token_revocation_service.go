@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+)
+
+// revocationCacheTTL ระยะเวลาที่ cache ผลลัพธ์ "ยังไม่ถูกเพิกถอน" ก่อนถาม Postgres ใหม่
+const revocationCacheTTL = 30 * time.Second
+
+// TokenRevocationService interface สำหรับเพิกถอน access token และตรวจสอบใน AuthMiddleware
+type TokenRevocationService interface {
+	RevokeToken(jti string, userID uint, expiresAt time.Time) error
+	RevokeAllForUser(userID uint) error
+	IsTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+// tokenRevocationService เก็บข้อมูลใน Postgres และ cache ไว้ใน memory
+type tokenRevocationService struct {
+	revocationRepo repositories.TokenRevocationRepository
+	refreshRepo    repositories.RefreshTokenRepository
+
+	mu sync.RWMutex
+	// revokedJTIs jti ที่ถูกเพิกถอน -> เวลาหมดอายุของ token
+	revokedJTIs map[string]time.Time
+	// checkedJTIs jti ที่ตรวจแล้วว่ายังไม่ถูกเพิกถอน -> เวลาที่ต้องตรวจใหม่
+	checkedJTIs map[string]time.Time
+	// userCutoffs เวลาเพิกถอนล่าสุดของแต่ละ user
+	userCutoffs map[uint]cachedCutoff
+}
+
+type cachedCutoff struct {
+	revokedBefore time.Time
+	checkAfter    time.Time
+}
+
+// NewTokenRevocationService creates new token revocation service instance
+func NewTokenRevocationService(revocationRepo repositories.TokenRevocationRepository, refreshRepo repositories.RefreshTokenRepository) TokenRevocationService {
+	return &tokenRevocationService{
+		revocationRepo: revocationRepo,
+		refreshRepo:    refreshRepo,
+		revokedJTIs:    make(map[string]time.Time),
+		checkedJTIs:    make(map[string]time.Time),
+		userCutoffs:    make(map[uint]cachedCutoff),
+	}
+}
+
+// RevokeToken เพิกถอน access token หนึ่งตัว (logout)
+func (s *tokenRevocationService) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	if jti == "" {
+		return fmt.Errorf("token has no jti")
+	}
+
+	// jti ที่หมดอายุแล้วไม่ต้องเก็บต่อ ลบทิ้งระหว่าง logout
+	if err := s.revocationRepo.DeleteExpired(); err != nil {
+		return err
+	}
+
+	if err := s.revocationRepo.RevokeToken(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.revokedJTIs[jti] = expiresAt
+	delete(s.checkedJTIs, jti)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// RevokeAllForUser เพิกถอน access token และ refresh token ทั้งหมดของ user (logout ทุกอุปกรณ์)
+func (s *tokenRevocationService) RevokeAllForUser(userID uint) error {
+	// Postgres เก็บเวลาละเอียดระดับ microsecond เท่ากับ iat ของ JWT
+	now := time.Now().Truncate(time.Microsecond)
+	if err := s.revocationRepo.RevokeAllForUser(userID, now); err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.userCutoffs[userID] = cachedCutoff{
+		revokedBefore: now,
+		checkAfter:    now.Add(revocationCacheTTL),
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// IsTokenRevoked ตรวจสอบว่า token ถูกเพิกถอนด้วย jti หรือด้วยการ logout ทุกอุปกรณ์
+func (s *tokenRevocationService) IsTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	cutoff, err := s.userCutoff(userID)
+	if err != nil {
+		return false, err
+	}
+	// iat ละเอียดระดับ microsecond: token ที่ออกหลังการเพิกถอน (เช่น login ใหม่ในวินาทีเดียวกัน) ยังใช้ได้
+	if !cutoff.IsZero() && issuedAt.Before(cutoff) {
+		return true, nil
+	}
+
+	if jti == "" {
+		return false, nil
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	_, revoked := s.revokedJTIs[jti]
+	checkAfter, checked := s.checkedJTIs[jti]
+	s.mu.RUnlock()
+
+	if revoked {
+		return true, nil
+	}
+	if checked && now.Before(checkAfter) {
+		return false, nil
+	}
+
+	revoked, err = s.revocationRepo.IsTokenRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.evictExpired(now)
+	if revoked {
+		// ไม่รู้เวลาหมดอายุจริง เก็บไว้นานเท่าอายุสูงสุดของ access token
+		s.revokedJTIs[jti] = now.Add(utils.AccessTokenTTL)
+		delete(s.checkedJTIs, jti)
+	} else {
+		s.checkedJTIs[jti] = now.Add(revocationCacheTTL)
+	}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+// userCutoff ดึงเวลาเพิกถอนของ user จาก cache หรือ Postgres
+func (s *tokenRevocationService) userCutoff(userID uint) (time.Time, error) {
+	now := time.Now()
+	s.mu.RLock()
+	cached, ok := s.userCutoffs[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.checkAfter) {
+		return cached.revokedBefore, nil
+	}
+
+	revocation, err := s.revocationRepo.GetUserRevocation(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var revokedBefore time.Time
+	if revocation != nil {
+		revokedBefore = revocation.RevokedBefore
+	}
+
+	s.mu.Lock()
+	s.userCutoffs[userID] = cachedCutoff{
+		revokedBefore: revokedBefore,
+		checkAfter:    now.Add(revocationCacheTTL),
+	}
+	s.mu.Unlock()
+
+	return revokedBefore, nil
+}
+
+// evictExpired ลบ entry ที่ไม่จำเป็นต้องเก็บแล้วออกจาก cache (ต้องถือ lock อยู่)
+func (s *tokenRevocationService) evictExpired(now time.Time) {
+	for jti, expiresAt := range s.revokedJTIs {
+		if now.After(expiresAt) {
+			delete(s.revokedJTIs, jti)
+		}
+	}
+	for jti, checkAfter := range s.checkedJTIs {
+		if now.After(checkAfter) {
+			delete(s.checkedJTIs, jti)
+		}
+	}
+	for userID, cached := range s.userCutoffs {
+		if now.After(cached.checkAfter) {
+			delete(s.userCutoffs, userID)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeRevocations token revocation repository ในหน่วยความจำ
+type fakeRevocations struct {
+	repositories.TokenRevocationRepository
+	cutoffs map[uint]time.Time
+}
+
+func (r *fakeRevocations) RevokeAllForUser(userID uint, before time.Time) error {
+	r.cutoffs[userID] = before
+	return nil
+}
+
+func (r *fakeRevocations) GetUserRevocation(userID uint) (*models.UserTokenRevocation, error) {
+	before, ok := r.cutoffs[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &models.UserTokenRevocation{UserID: userID, RevokedBefore: before}, nil
+}
+
+func (r *fakeRevocations) IsTokenRevoked(jti string) (bool, error) {
+	return false, nil
+}
+
+// fakeRefreshTokens refresh token repository ที่ไม่เก็บอะไร
+type fakeRefreshTokens struct {
+	repositories.RefreshTokenRepository
+}
+
+func (fakeRefreshTokens) RevokeAllForUser(userID uint) error {
+	return nil
+}
+
+// issuedAt คืน iat ของ token ที่ออกตอนนี้หลังผ่าน JSON เหมือนที่ AuthMiddleware อ่าน
+func issuedAt(t *testing.T) time.Time {
+	t.Helper()
+	data, err := json.Marshal(jwt.NewNumericDate(time.Now()))
+	if err != nil {
+		t.Fatalf("failed to marshal iat: %v", err)
+	}
+	var iat jwt.NumericDate
+	if err := json.Unmarshal(data, &iat); err != nil {
+		t.Fatalf("failed to unmarshal iat: %v", err)
+	}
+	return iat.Time
+}
+
+func TestRevokeAllForUser(t *testing.T) {
+	for _, cached := range []bool{true, false} {
+		revocationRepo := &fakeRevocations{cutoffs: make(map[uint]time.Time)}
+		service := NewTokenRevocationService(revocationRepo, fakeRefreshTokens{})
+
+		before := issuedAt(t)
+		time.Sleep(time.Millisecond)
+		if err := service.RevokeAllForUser(7); err != nil {
+			t.Fatalf("RevokeAllForUser error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+		after := issuedAt(t)
+
+		if !cached {
+			// อ่าน cutoff จาก repository แทน cache ของ instance ที่เพิกถอน
+			service = NewTokenRevocationService(revocationRepo, fakeRefreshTokens{})
+		}
+
+		tests := []struct {
+			name        string
+			userID      uint
+			issuedAt    time.Time
+			wantRevoked bool
+		}{
+			{name: "token issued before revocation", userID: 7, issuedAt: before, wantRevoked: true},
+			{name: "token issued just after revocation", userID: 7, issuedAt: after, wantRevoked: false},
+			{name: "token of another user", userID: 8, issuedAt: before, wantRevoked: false},
+		}
+		for _, tt := range tests {
+			revoked, err := service.IsTokenRevoked("jti", tt.userID, tt.issuedAt)
+			if err != nil {
+				t.Fatalf("%s (cached %v): IsTokenRevoked error: %v", tt.name, cached, err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("%s (cached %v): revoked = %v, want %v", tt.name, cached, revoked, tt.wantRevoked)
+			}
+		}
+	}
+}
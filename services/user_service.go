@@ -1,38 +1,58 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"collp-backend/models"
+	"collp-backend/oidc"
 	"collp-backend/repositories"
 )
 
 // UserService interface สำหรับ user business logic
 type UserService interface {
 	// User management
-	CreateUser(email, name, googleID, avatar string) (*models.User, error)
-	GetOrCreateUser(email, name, googleID, avatar string) (*models.User, error)
+	CreateUser(user *models.User) (*models.User, error)
+	CreateOrganizationUser(organizationID uint, user *models.User) (*models.User, error)
+	GetOrCreateUser(identity *oidc.Identity) (*models.User, error)
 	GetUserByID(id uint) (*models.User, error)
+	GetOrganizationUser(organizationID, id uint) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	UpdateUserProfile(id uint, name, avatar string) error
 	DeactivateUser(id uint) error
 	ActivateUser(id uint) error
 	DeleteUser(id uint) error
+	HardDeleteUser(id uint) error
+	UnlockUser(id, actorID uint) error
+	RemoveFromOrganization(actorID, organizationID, id uint) error
+
+	// Roles and permissions
+	GetRoles() ([]*models.Role, error)
+	GetRolePermissions(roleName string) ([]string, error)
+	AssignRole(id uint, roleName string) error
 
 	// User queries
-	GetAllUsers(page, limit int) ([]*models.User, int64, error)
+	GetAllUsers(organizationID uint, page, limit int) ([]*models.User, int64, error)
 	GetActiveUsers() ([]*models.User, error)
-	SearchUsers(keyword string, page, limit int) ([]*models.User, int64, error)
+	SearchUsers(organizationID uint, keyword string, page, limit int) ([]*models.User, int64, error)
 
 	// Statistics
-	GetUserStats() (*UserStats, error)
+	GetUserStats(organizationID uint) (*UserStats, error)
 
 	// Validation
 	IsValidEmail(email string) bool
 	IsUserActive(id uint) (bool, error)
 }
 
+var (
+	// ErrUserExists มี user ที่ใช้ email นี้อยู่แล้ว
+	ErrUserExists = errors.New("user already exists")
+	// ErrInvalidInput ข้อมูลที่ส่งมาไม่ถูกต้อง
+	ErrInvalidInput = errors.New("invalid input")
+)
+
 // UserStats สถิติของ users
 type UserStats struct {
 	TotalUsers    int64 `json:"total_users"`
@@ -42,84 +62,216 @@ type UserStats struct {
 
 // userService struct implements UserService interface
 type userService struct {
-	userRepo repositories.UserRepository
+	userRepo     repositories.UserRepository
+	roleRepo     repositories.RoleRepository
+	identityRepo repositories.UserIdentityRepository
+	memberships  repositories.MembershipRepository
+	revocations  TokenRevocationService
+	audit        AuditService
 }
 
 // NewUserService creates new user service instance
-func NewUserService(userRepo repositories.UserRepository) UserService {
+func NewUserService(userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, identityRepo repositories.UserIdentityRepository, memberships repositories.MembershipRepository, revocations TokenRevocationService, audit AuditService) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		identityRepo: identityRepo,
+		memberships:  memberships,
+		revocations:  revocations,
+		audit:        audit,
 	}
 }
 
 // CreateUser สร้าง user ใหม่
-func (s *userService) CreateUser(email, name, googleID, avatar string) (*models.User, error) {
+func (s *userService) CreateUser(user *models.User) (*models.User, error) {
+	if err := s.prepareNewUser(user); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// CreateOrganizationUser สร้าง user ใหม่เป็น member ของ organization ที่ใช้งานอยู่
+func (s *userService) CreateOrganizationUser(organizationID uint, user *models.User) (*models.User, error) {
+	if organizationID == 0 {
+		return nil, ErrNoActiveOrganization
+	}
+	if err := s.prepareNewUser(user); err != nil {
+		return nil, err
+	}
+
+	membership := &models.Membership{
+		OrganizationID: organizationID,
+		Role:           models.OrgRoleMember,
+	}
+	if err := s.userRepo.CreateInOrganization(user, membership); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// prepareNewUser ตรวจสอบข้อมูลของ user ใหม่และตั้งค่าเริ่มต้น
+func (s *userService) prepareNewUser(user *models.User) error {
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	user.Name = strings.TrimSpace(user.Name)
+
 	// Validate email
-	if !s.IsValidEmail(email) {
-		return nil, fmt.Errorf("invalid email format: %s", email)
+	if !s.IsValidEmail(user.Email) {
+		return fmt.Errorf("%w: invalid email format: %s", ErrInvalidInput, user.Email)
 	}
 
 	// Validate required fields
-	if strings.TrimSpace(name) == "" {
-		return nil, fmt.Errorf("name is required")
+	if user.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
 	}
 
 	// Check if user already exists
-	exists, err := s.userRepo.ExistsByEmail(email)
+	exists, err := s.userRepo.ExistsByEmail(user.Email)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing user: %w", err)
+		return fmt.Errorf("failed to check existing user: %w", err)
 	}
 	if exists {
-		return nil, fmt.Errorf("user with email %s already exists", email)
+		return fmt.Errorf("%w: %s", ErrUserExists, user.Email)
 	}
 
-	// Create user
-	user := &models.User{
-		Email:    strings.ToLower(strings.TrimSpace(email)),
-		Name:     strings.TrimSpace(name),
-		GoogleID: googleID,
-		Avatar:   avatar,
-		IsActive: true,
+	user.IsActive = true
+	if user.Role == "" {
+		user.Role = models.RoleMember
 	}
-
-	if err := s.userRepo.Create(user); err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
-	}
-
-	return user, nil
+	return nil
 }
 
-// GetOrCreateUser ดึง user หรือสร้างใหม่ถ้าไม่มี (สำหรับ OAuth)
-func (s *userService) GetOrCreateUser(email, name, googleID, avatar string) (*models.User, error) {
+// GetOrCreateUser ดึง user ที่ผูกกับ identity ของ OIDC provider หรือสร้างใหม่ถ้ายังไม่มี
+// ผูกกับ user เดิมที่ใช้ email เดียวกันอัตโนมัติเฉพาะเมื่อ provider ยืนยัน email แล้วเท่านั้น
+func (s *userService) GetOrCreateUser(identity *oidc.Identity) (*models.User, error) {
 	// Normalize email
-	email = strings.ToLower(strings.TrimSpace(email))
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+
+	// login ครั้งถัดไปของ identity ที่ผูกไว้แล้ว
+	linked, err := s.identityRepo.GetByProviderSubject(identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(linked.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get linked user: %w", err)
+		}
+		if err := s.identityRepo.RecordLogin(linked.ID, email, identity.EmailVerified); err != nil {
+			return nil, err
+		}
+		if err := s.updateFromIdentity(user, email, identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repositories.ErrUserIdentityNotFound) {
+		return nil, err
+	}
 
 	// Validate email
 	if !s.IsValidEmail(email) {
-		return nil, fmt.Errorf("invalid email format: %s", email)
+		return nil, fmt.Errorf("%w: invalid email format: %s", ErrInvalidInput, email)
 	}
 
-	// Create user data
-	userData := &models.User{
-		Email:    email,
-		Name:     strings.TrimSpace(name),
-		GoogleID: googleID,
-		Avatar:   avatar,
-		IsActive: true,
+	now := time.Now()
+	newIdentity := &models.UserIdentity{
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         email,
+		EmailVerified: identity.EmailVerified,
+		LastLoginAt:   &now,
 	}
 
-	user, err := s.userRepo.CreateIfNotExists(email, googleID, userData)
+	user, err := s.userRepo.GetByEmail(email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		// Create user data
+		user = &models.User{
+			Email:    email,
+			Name:     strings.TrimSpace(identity.Name),
+			Avatar:   identity.Picture,
+			Role:     models.RoleMember,
+			IsActive: true,
+		}
+		if identity.EmailVerified {
+			user.EmailVerifiedAt = &now
+		}
+		if err := s.identityRepo.CreateWithUser(user, newIdentity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get or create user: %w", err)
+		return nil, err
+	}
+
+	// email ที่ provider ไม่ยืนยันอาจเป็นของคนอื่น ต้อง login แล้วผูกเองเท่านั้น
+	if !identity.EmailVerified {
+		return nil, ErrIdentityLinkRequired
+	}
+
+	newIdentity.UserID = user.ID
+
+	// บัญชีที่ยังไม่ยืนยัน email อาจถูกคนอื่นสมัครไว้ก่อน (pre-account takeover) ด้วย password
+	// หรือ provider ที่ไม่ยืนยัน email แล้วเพิ่ม passkey, TOTP หรือ PAT ไว้ เจ้าของ email ตัวจริงคือผู้ที่
+	// login ผ่าน provider จึงลบวิธี login เดิมทั้งหมดและเพิกถอน token เดิม
+	if user.EmailVerifiedAt == nil {
+		if err := s.identityRepo.CreateAndResetCredentials(newIdentity); err != nil {
+			return nil, err
+		}
+		user.PasswordHash = ""
+		user.TOTPSecret = ""
+		user.TOTPEnabledAt = nil
+		user.TOTPLastUsedStep = 0
+		if err := s.revocations.RevokeAllForUser(user.ID); err != nil {
+			return nil, err
+		}
+		s.audit.Record(&models.AuditLog{
+			Action:  models.AuditAccountReclaimed,
+			UserID:  &user.ID,
+			Details: fmt.Sprintf("removed existing sign-in methods of unverified account on %s login with verified email %s", identity.Provider, email),
+		})
+	} else if err := s.identityRepo.Create(newIdentity); err != nil {
+		return nil, err
+	}
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditIdentityLinked,
+		UserID:  &user.ID,
+		Details: fmt.Sprintf("auto-linked %s account by verified email %s", identity.Provider, email),
+	})
+
+	if err := s.updateFromIdentity(user, email, identity); err != nil {
+		return nil, err
 	}
-
 	return user, nil
 }
 
+// updateFromIdentity อัพเดทรูปและสถานะยืนยัน email ของ user จากข้อมูลของ provider
+func (s *userService) updateFromIdentity(user *models.User, email string, identity *oidc.Identity) error {
+	fields := map[string]interface{}{}
+	if identity.Picture != "" && user.Avatar != identity.Picture {
+		fields["avatar"] = identity.Picture
+		user.Avatar = identity.Picture
+	}
+	if identity.EmailVerified && user.EmailVerifiedAt == nil && user.Email == email {
+		now := time.Now()
+		fields["email_verified_at"] = now
+		user.EmailVerifiedAt = &now
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := s.userRepo.UpdateFields(user.ID, fields); err != nil {
+		return fmt.Errorf("failed to update user from OAuth profile: %w", err)
+	}
+	return nil
+}
+
 // GetUserByID ดึง user ด้วย ID
 func (s *userService) GetUserByID(id uint) (*models.User, error) {
 	if id == 0 {
-		return nil, fmt.Errorf("invalid user id")
+		return nil, fmt.Errorf("%w: invalid user id", ErrInvalidInput)
 	}
 
 	user, err := s.userRepo.GetByID(id)
@@ -130,12 +282,30 @@ func (s *userService) GetUserByID(id uint) (*models.User, error) {
 	return user, nil
 }
 
+// GetOrganizationUser ดึง user ด้วย ID เฉพาะสมาชิกของ organization ที่ใช้งานอยู่
+// user ของ organization อื่นคืน ErrUserNotFound เหมือนไม่มี user นั้น
+func (s *userService) GetOrganizationUser(organizationID, id uint) (*models.User, error) {
+	if organizationID == 0 {
+		return nil, ErrNoActiveOrganization
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("%w: invalid user id", ErrInvalidInput)
+	}
+
+	user, err := s.userRepo.GetByIDInOrganization(organizationID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
 // GetUserByEmail ดึง user ด้วย email
 func (s *userService) GetUserByEmail(email string) (*models.User, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 
 	if !s.IsValidEmail(email) {
-		return nil, fmt.Errorf("invalid email format")
+		return nil, fmt.Errorf("%w: invalid email format", ErrInvalidInput)
 	}
 
 	user, err := s.userRepo.GetByEmail(email)
@@ -149,13 +319,13 @@ func (s *userService) GetUserByEmail(email string) (*models.User, error) {
 // UpdateUserProfile อัพเดท profile ของ user
 func (s *userService) UpdateUserProfile(id uint, name, avatar string) error {
 	if id == 0 {
-		return fmt.Errorf("invalid user id")
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
 	}
 
 	// Validate name
 	name = strings.TrimSpace(name)
 	if name == "" {
-		return fmt.Errorf("name is required")
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
 	}
 
 	// Check if user exists
@@ -164,7 +334,7 @@ func (s *userService) UpdateUserProfile(id uint, name, avatar string) error {
 		return fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if !exists {
-		return fmt.Errorf("user not found")
+		return repositories.ErrUserNotFound
 	}
 
 	// Update user
@@ -183,20 +353,25 @@ func (s *userService) UpdateUserProfile(id uint, name, avatar string) error {
 // DeactivateUser ปิดการใช้งาน user
 func (s *userService) DeactivateUser(id uint) error {
 	if id == 0 {
-		return fmt.Errorf("invalid user id")
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
 	}
 
 	if err := s.userRepo.UpdateStatus(id, false); err != nil {
 		return fmt.Errorf("failed to deactivate user: %w", err)
 	}
 
+	// token ที่ยังไม่หมดอายุของ user ต้องใช้ไม่ได้ทันที
+	if err := s.revocations.RevokeAllForUser(id); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
 	return nil
 }
 
 // ActivateUser เปิดการใช้งาน user
 func (s *userService) ActivateUser(id uint) error {
 	if id == 0 {
-		return fmt.Errorf("invalid user id")
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
 	}
 
 	if err := s.userRepo.UpdateStatus(id, true); err != nil {
@@ -209,7 +384,7 @@ func (s *userService) ActivateUser(id uint) error {
 // DeleteUser ลบ user (soft delete)
 func (s *userService) DeleteUser(id uint) error {
 	if id == 0 {
-		return fmt.Errorf("invalid user id")
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
 	}
 
 	// Check if user exists
@@ -218,18 +393,160 @@ func (s *userService) DeleteUser(id uint) error {
 		return fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if !exists {
-		return fmt.Errorf("user not found")
+		return repositories.ErrUserNotFound
 	}
 
 	if err := s.userRepo.Delete(id); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	if err := s.revocations.RevokeAllForUser(id); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	return nil
+}
+
+// HardDeleteUser ลบ user ออกจากฐานข้อมูลถาวร
+func (s *userService) HardDeleteUser(id uint) error {
+	if id == 0 {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
+	}
+
+	if err := s.revocations.RevokeAllForUser(id); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	if err := s.userRepo.HardDelete(id); err != nil {
+		return fmt.Errorf("failed to hard delete user: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockUser ปลดล็อคบัญชีที่ถูกล็อคจากการ login ผิด (admin)
+func (s *userService) UnlockUser(id, actorID uint) error {
+	if id == 0 {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
+	}
+
+	if err := s.userRepo.ResetFailedLogins(id); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditAccountUnlocked,
+		ActorID: &actorID,
+		UserID:  &id,
+	})
+
+	return nil
+}
+
+// RemoveFromOrganization นำ user ออกจาก organization ที่ใช้งานอยู่โดยไม่แตะบัญชีของ user
+// (ใช้แทนการปิดการใช้งานหรือลบบัญชีเมื่อผู้เรียกไม่ใช่ admin ของทั้งระบบ)
+func (s *userService) RemoveFromOrganization(actorID, organizationID, id uint) error {
+	if organizationID == 0 {
+		return ErrNoActiveOrganization
+	}
+	if id == 0 {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
+	}
+
+	membership, err := s.memberships.Get(organizationID, id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrMembershipNotFound) {
+			return fmt.Errorf("%w: id %d in organization %d", repositories.ErrUserNotFound, id, organizationID)
+		}
+		return err
+	}
+	if membership.Role == models.OrgRoleOwner {
+		owners, err := s.memberships.CountByRole(organizationID, models.OrgRoleOwner)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return ErrLastOwner
+		}
+	}
+
+	if err := s.memberships.Delete(organizationID, id); err != nil {
+		return err
+	}
+
+	// token เดิมยังมี org_id ของ organization นี้
+	if err := s.revocations.RevokeAllForUser(id); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditMemberRemoved,
+		ActorID: &actorID,
+		UserID:  &id,
+		Details: fmt.Sprintf("organization %d (was %s)", organizationID, membership.Role),
+	})
+	return nil
+}
+
+// GetRoles ดึง roles ทั้งหมดพร้อม permissions
+func (s *userService) GetRoles() ([]*models.Role, error) {
+	roles, err := s.roleRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetRolePermissions ดึงชื่อ permissions ของ role (role ที่ไม่มีในระบบจะไม่มีสิทธิ์ใดๆ)
+func (s *userService) GetRolePermissions(roleName string) ([]string, error) {
+	permissions, err := s.roleRepo.GetPermissionNames(roleName)
+	if err != nil {
+		if errors.Is(err, repositories.ErrRoleNotFound) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// AssignRole เปลี่ยน role ของ user และเพิกถอน token เดิมที่มี permissions ชุดเก่า
+func (s *userService) AssignRole(id uint, roleName string) error {
+	if id == 0 {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidInput)
+	}
+
+	if _, err := s.roleRepo.GetByName(roleName); err != nil {
+		if errors.Is(err, repositories.ErrRoleNotFound) {
+			return fmt.Errorf("%w: unknown role %s", ErrInvalidInput, roleName)
+		}
+		return err
+	}
+
+	exists, err := s.userRepo.Exists(id)
+	if err != nil {
+		return fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if !exists {
+		return repositories.ErrUserNotFound
+	}
+
+	if err := s.userRepo.UpdateFields(id, map[string]interface{}{"role": roleName}); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	if err := s.revocations.RevokeAllForUser(id); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
 	return nil
 }
 
-// GetAllUsers ดึง users ทั้งหมดแบบ pagination
-func (s *userService) GetAllUsers(page, limit int) ([]*models.User, int64, error) {
+// GetAllUsers ดึง users ของ organization ที่ใช้งานอยู่แบบ pagination
+func (s *userService) GetAllUsers(organizationID uint, page, limit int) ([]*models.User, int64, error) {
+	if organizationID == 0 {
+		return nil, 0, ErrNoActiveOrganization
+	}
+
 	// Validate pagination parameters
 	if page <= 0 {
 		page = 1
@@ -238,7 +555,7 @@ func (s *userService) GetAllUsers(page, limit int) ([]*models.User, int64, error
 		limit = 10
 	}
 
-	users, total, err := s.userRepo.GetAll(page, limit)
+	users, total, err := s.userRepo.GetAll(organizationID, page, limit)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -256,12 +573,16 @@ func (s *userService) GetActiveUsers() ([]*models.User, error) {
 	return users, nil
 }
 
-// SearchUsers ค้นหา users
-func (s *userService) SearchUsers(keyword string, page, limit int) ([]*models.User, int64, error) {
+// SearchUsers ค้นหา users ใน organization ที่ใช้งานอยู่
+func (s *userService) SearchUsers(organizationID uint, keyword string, page, limit int) ([]*models.User, int64, error) {
+	if organizationID == 0 {
+		return nil, 0, ErrNoActiveOrganization
+	}
+
 	// Validate search keyword
 	keyword = strings.TrimSpace(keyword)
 	if keyword == "" {
-		return nil, 0, fmt.Errorf("search keyword is required")
+		return nil, 0, fmt.Errorf("%w: search keyword is required", ErrInvalidInput)
 	}
 
 	// Validate pagination
@@ -272,7 +593,7 @@ func (s *userService) SearchUsers(keyword string, page, limit int) ([]*models.Us
 		limit = 10
 	}
 
-	users, total, err := s.userRepo.Search(keyword, page, limit)
+	users, total, err := s.userRepo.Search(organizationID, keyword, page, limit)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to search users: %w", err)
 	}
@@ -280,14 +601,18 @@ func (s *userService) SearchUsers(keyword string, page, limit int) ([]*models.Us
 	return users, total, nil
 }
 
-// GetUserStats ดึงสถิติของ users
-func (s *userService) GetUserStats() (*UserStats, error) {
-	totalUsers, err := s.userRepo.Count()
+// GetUserStats ดึงสถิติของสมาชิกใน organization
+func (s *userService) GetUserStats(organizationID uint) (*UserStats, error) {
+	if organizationID == 0 {
+		return nil, ErrNoActiveOrganization
+	}
+
+	totalUsers, err := s.userRepo.Count(organizationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count total users: %w", err)
 	}
 
-	activeUsers, err := s.userRepo.CountActive()
+	activeUsers, err := s.userRepo.CountActive(organizationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count active users: %w", err)
 	}
@@ -315,7 +640,7 @@ func (s *userService) IsValidEmail(email string) bool {
 // IsUserActive ตรวจสอบว่า user active หรือไม่
 func (s *userService) IsUserActive(id uint) (bool, error) {
 	if id == 0 {
-		return false, fmt.Errorf("invalid user id")
+		return false, fmt.Errorf("%w: invalid user id", ErrInvalidInput)
 	}
 
 	user, err := s.userRepo.GetByID(id)
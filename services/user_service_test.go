@@ -0,0 +1,281 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/oidc"
+	"collp-backend/repositories"
+)
+
+// fakeEmailUsers user repository ที่หา user ด้วย email
+type fakeEmailUsers struct {
+	repositories.UserRepository
+	user *models.User
+}
+
+func (r *fakeEmailUsers) GetByEmail(email string) (*models.User, error) {
+	if r.user == nil || r.user.Email != email {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *r.user
+	return &copied, nil
+}
+
+func (r *fakeEmailUsers) UpdateFields(id uint, fields map[string]interface{}) error {
+	return nil
+}
+
+// fakeIdentities identity repository ที่บันทึกวิธีผูก identity
+type fakeIdentities struct {
+	repositories.UserIdentityRepository
+	created []*models.UserIdentity
+	reset   []*models.UserIdentity
+}
+
+func (r *fakeIdentities) GetByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	return nil, repositories.ErrUserIdentityNotFound
+}
+
+func (r *fakeIdentities) Create(identity *models.UserIdentity) error {
+	r.created = append(r.created, identity)
+	return nil
+}
+
+func (r *fakeIdentities) CreateAndResetCredentials(identity *models.UserIdentity) error {
+	r.reset = append(r.reset, identity)
+	return nil
+}
+
+// fakeUserRevocations บันทึก user ที่ถูกเพิกถอน token ทั้งหมด
+type fakeUserRevocations struct {
+	TokenRevocationService
+	revoked []uint
+}
+
+func (r *fakeUserRevocations) RevokeAllForUser(userID uint) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+func TestGetOrCreateUserLinksExistingAccount(t *testing.T) {
+	verifiedAt := time.Now().Add(-time.Hour)
+	enabledAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		user          *models.User
+		emailVerified bool
+		wantErr       error
+		wantReset     bool
+	}{
+		{
+			name:          "unverified account with password and TOTP",
+			user:          &models.User{ID: 7, Email: "user@example.com", PasswordHash: "hash", TOTPSecret: "secret", TOTPEnabledAt: &enabledAt, IsActive: true},
+			emailVerified: true,
+			wantReset:     true,
+		},
+		{
+			name:          "unverified account without password",
+			user:          &models.User{ID: 7, Email: "user@example.com", IsActive: true},
+			emailVerified: true,
+			wantReset:     true,
+		},
+		{
+			name:          "verified account",
+			user:          &models.User{ID: 7, Email: "user@example.com", PasswordHash: "hash", EmailVerifiedAt: &verifiedAt, IsActive: true},
+			emailVerified: true,
+		},
+		{
+			name:          "email not verified by provider",
+			user:          &models.User{ID: 7, Email: "user@example.com", PasswordHash: "hash", IsActive: true},
+			emailVerified: false,
+			wantErr:       ErrIdentityLinkRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		identities := &fakeIdentities{}
+		revocations := &fakeUserRevocations{}
+		audit := &fakeAudit{}
+		service := NewUserService(&fakeEmailUsers{user: tt.user}, nil, identities, nil, revocations, audit)
+
+		user, err := service.GetOrCreateUser(&oidc.Identity{
+			Provider:      "google",
+			Subject:       "subject-1",
+			Email:         "User@example.com",
+			EmailVerified: tt.emailVerified,
+		})
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if len(identities.created)+len(identities.reset) != 0 || len(revocations.revoked) != 0 {
+				t.Errorf("%s: identity linked after error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: GetOrCreateUser error: %v", tt.name, err)
+		}
+
+		if tt.wantReset {
+			if len(identities.reset) != 1 || len(identities.created) != 0 || identities.reset[0].UserID != 7 {
+				t.Errorf("%s: identity not linked with credential reset", tt.name)
+			}
+			if len(revocations.revoked) != 1 || revocations.revoked[0] != 7 {
+				t.Errorf("%s: revoked = %v, want [7]", tt.name, revocations.revoked)
+			}
+			if user.PasswordHash != "" || user.TOTPSecret != "" || user.MFAEnabled() {
+				t.Errorf("%s: returned user still has password or TOTP", tt.name)
+			}
+			if audit.actions[0] != models.AuditAccountReclaimed {
+				t.Errorf("%s: audit actions = %v, want %s first", tt.name, audit.actions, models.AuditAccountReclaimed)
+			}
+		} else {
+			if len(identities.created) != 1 || len(identities.reset) != 0 {
+				t.Errorf("%s: identity not linked without reset", tt.name)
+			}
+			if len(revocations.revoked) != 0 {
+				t.Errorf("%s: tokens of verified account revoked", tt.name)
+			}
+			if user.PasswordHash != "hash" {
+				t.Errorf("%s: password of verified account cleared", tt.name)
+			}
+		}
+		if user.EmailVerifiedAt == nil {
+			t.Errorf("%s: email not marked verified", tt.name)
+		}
+	}
+}
+
+// fakeOrganizationUsers user repository ที่รู้ว่า user เป็นสมาชิก organization ใด
+type fakeOrganizationUsers struct {
+	repositories.UserRepository
+	members map[uint][]uint
+}
+
+func (r *fakeOrganizationUsers) GetByIDInOrganization(organizationID, id uint) (*models.User, error) {
+	for _, member := range r.members[organizationID] {
+		if member == id {
+			return &models.User{ID: id}, nil
+		}
+	}
+	return nil, repositories.ErrUserNotFound
+}
+
+func TestGetOrganizationUser(t *testing.T) {
+	service := NewUserService(&fakeOrganizationUsers{members: map[uint][]uint{1: {7}, 2: {8}}}, nil, nil, nil, nil, nil)
+
+	tests := []struct {
+		name           string
+		organizationID uint
+		id             uint
+		wantErr        error
+	}{
+		{name: "member of active organization", organizationID: 1, id: 7},
+		{name: "member of another organization", organizationID: 1, id: 8, wantErr: repositories.ErrUserNotFound},
+		{name: "unknown user", organizationID: 1, id: 9, wantErr: repositories.ErrUserNotFound},
+		{name: "no active organization", organizationID: 0, id: 7, wantErr: ErrNoActiveOrganization},
+		{name: "invalid user id", organizationID: 1, id: 0, wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		user, err := service.GetOrganizationUser(tt.organizationID, tt.id)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || user.ID != tt.id {
+			t.Errorf("%s: user = %v, error = %v, want user %d", tt.name, user, err, tt.id)
+		}
+	}
+}
+
+// fakeNewUsers user repository ที่บันทึก user และ membership ที่สร้าง
+type fakeNewUsers struct {
+	repositories.UserRepository
+	memberships []*models.Membership
+}
+
+func (r *fakeNewUsers) ExistsByEmail(email string) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeNewUsers) CreateInOrganization(user *models.User, membership *models.Membership) error {
+	user.ID = 7
+	membership.UserID = user.ID
+	r.memberships = append(r.memberships, membership)
+	return nil
+}
+
+func TestCreateOrganizationUser(t *testing.T) {
+	users := &fakeNewUsers{}
+	service := NewUserService(users, nil, nil, nil, nil, nil)
+
+	if _, err := service.CreateOrganizationUser(0, &models.User{Email: "new@example.com", Name: "New"}); !errors.Is(err, ErrNoActiveOrganization) {
+		t.Errorf("no active organization: error = %v, want %v", err, ErrNoActiveOrganization)
+	}
+
+	user, err := service.CreateOrganizationUser(3, &models.User{Email: " New@Example.com ", Name: "New"})
+	if err != nil {
+		t.Fatalf("CreateOrganizationUser error: %v", err)
+	}
+	if user.Email != "new@example.com" || !user.IsActive || user.Role != models.RoleMember {
+		t.Errorf("user = %+v, want active member new@example.com", user)
+	}
+	want := models.Membership{OrganizationID: 3, UserID: 7, Role: models.OrgRoleMember}
+	if len(users.memberships) != 1 || *users.memberships[0] != want {
+		t.Errorf("memberships = %v, want [%+v]", users.memberships, want)
+	}
+}
+
+func TestRemoveFromOrganization(t *testing.T) {
+	tests := []struct {
+		name           string
+		organizationID uint
+		id             uint
+		wantErr        error
+	}{
+		{name: "member", organizationID: 1, id: 7},
+		{name: "one of several owners", organizationID: 1, id: 8},
+		{name: "last owner", organizationID: 2, id: 8, wantErr: ErrLastOwner},
+		{name: "member of another organization", organizationID: 2, id: 7, wantErr: repositories.ErrUserNotFound},
+		{name: "no active organization", organizationID: 0, id: 7, wantErr: ErrNoActiveOrganization},
+	}
+
+	for _, tt := range tests {
+		memberships := &fakeOrgMemberships{list: []*models.Membership{
+			{OrganizationID: 1, UserID: 1, Role: models.OrgRoleOwner},
+			{OrganizationID: 1, UserID: 7, Role: models.OrgRoleMember},
+			{OrganizationID: 1, UserID: 8, Role: models.OrgRoleOwner},
+			{OrganizationID: 2, UserID: 8, Role: models.OrgRoleOwner},
+		}}
+		revocations := &fakeUserRevocations{}
+		service := NewUserService(nil, nil, nil, memberships, revocations, &fakeAudit{})
+
+		err := service.RemoveFromOrganization(1, tt.organizationID, tt.id)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if len(memberships.list) != 4 || len(revocations.revoked) != 0 {
+				t.Errorf("%s: membership removed or tokens revoked after error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: RemoveFromOrganization error: %v", tt.name, err)
+		}
+		if _, err := memberships.Get(tt.organizationID, tt.id); !errors.Is(err, repositories.ErrMembershipNotFound) {
+			t.Errorf("%s: membership still exists", tt.name)
+		}
+		if len(revocations.revoked) != 1 || revocations.revoked[0] != tt.id {
+			t.Errorf("%s: revoked = %v, want [%d]", tt.name, revocations.revoked, tt.id)
+		}
+	}
+}
@@ -1,19 +1,100 @@
 package services
 
-func CollPLoginUsecase(username, password string) map[string]interface{} {
-	return map[string]interface{}{
-		"username": username,
-		"password": password,
-		"status":   "Login success",
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+	"collp-backend/validators"
+)
+
+// dummyPasswordHash bcrypt hash (cost เดียวกับ utils.HashPassword) ที่ใช้เทียบแทนเมื่อไม่พบ user หรือ user ไม่มี password
+// login ด้วย email ที่ไม่มีอยู่จึงใช้เวลาเท่ากับ password ผิด และเดา email ที่สมัครไว้จากเวลาตอบไม่ได้
+const dummyPasswordHash = "$2a$14$kkSIIH8GEC3EiNFv8i/Meun01hwYlgEptzz5kJuEUQseG3fqhMi8W"
+
+// Login ตรวจสอบ email/password แล้วออก JWT ให้ user
+// login ผิดติดกันจะถูกหน่วงเวลาและล็อคทั้งบัญชีและ IP (ดู LoginThrottleService)
+func (s *AuthService) Login(req validators.UserLoginRequest, client ClientInfo) (*AuthResult, error) {
+	user, err := s.userRepo.GetByEmail(utils.SanitizeString(req.Email))
+	if err != nil {
+		if !errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		user = nil
+	}
+
+	// user ที่สมัครผ่าน OIDC provider อย่างเดียวจะไม่มี password hash แต่ยังต้องเทียบ bcrypt ให้ใช้เวลาเท่ากัน
+	// เทียบก่อนตรวจการล็อคด้วย ไม่อย่างนั้นบัญชีที่ถูกล็อคจะตอบเร็วกว่าและเดาได้ว่ามี email นี้อยู่
+	passwordHash := dummyPasswordHash
+	if user != nil && user.PasswordHash != "" {
+		passwordHash = user.PasswordHash
+	}
+	passwordValid := utils.CheckPasswordHash(req.Password, passwordHash)
+
+	// ตรวจการล็อคก่อนใช้ผลการเทียบ password เพื่อไม่ให้ใช้เดา password ระหว่างถูกล็อค
+	if err := s.throttle.Check(user, client.IP); err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.PasswordHash == "" || !passwordValid {
+		if err := s.throttle.RecordFailure(user, client.IP); err != nil {
+			return nil, fmt.Errorf("failed to record failed login: %w", err)
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
 	}
+
+	if err := s.throttle.RecordSuccess(user); err != nil {
+		return nil, fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+
+	return s.completeLogin(user, client, req.InvitationToken)
 }
 
-func CollPRegisterUsecase(username, password, phone, address string) map[string]interface{} {
-	return map[string]interface{}{
-		"username": username,
-		"password": password,
-		"phone":    phone,
-		"address":  address,
-		"status":   "register success",
+// Register สมัครสมาชิกด้วย email/password แล้วออก JWT ให้ใช้งานได้ทันที
+func (s *AuthService) Register(req validators.UserRegistrationRequest, client ClientInfo) (*AuthResult, error) {
+	// ตรวจคำเชิญก่อนสร้างบัญชี จะได้ไม่เหลือบัญชีที่สมัครด้วยคำเชิญที่ใช้ไม่ได้
+	if req.InvitationToken != "" {
+		invitation, err := s.invitations.GetInvitation(req.InvitationToken)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(invitation.Email, utils.SanitizeString(req.Email)) {
+			return nil, ErrInvitationEmailMismatch
+		}
+	}
+
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user, err := s.userService.CreateUser(&models.User{
+		Email:        req.Email,
+		Name:         req.Name,
+		PasswordHash: passwordHash,
+		Phone:        strings.TrimSpace(req.Phone),
+		Address:      strings.TrimSpace(req.Address),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// บัญชีใหม่ยังไม่มีปัจจัยที่สองให้รอ ตอบรับคำเชิญ (ที่ตรวจแล้วข้างบน) ทันทีเพื่อให้ email ถือว่ายืนยันแล้ว
+	invitationError := s.acceptInvitation(req.InvitationToken, user)
+
+	result, err := s.completeLogin(user, client, "")
+	if err != nil {
+		return nil, err
 	}
+	result.InvitationError = invitationError
+	// user เพิ่งกรอกข้อมูลเอง คืน user ได้แม้ต้องลงทะเบียน 2FA ก่อน
+	result.User = user
+	return result, nil
 }
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+	"collp-backend/utils"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+var (
+	// ErrInvalidPasskeySession session_token ไม่ถูกต้อง หมดอายุ หรือถูกใช้ไปแล้ว
+	ErrInvalidPasskeySession = errors.New("invalid, expired or already used passkey session")
+	// ErrPasskeyVerificationFailed authenticator response ไม่ผ่านการตรวจสอบ
+	ErrPasskeyVerificationFailed = errors.New("passkey verification failed")
+	// ErrPasskeyCloned sign counter ถอยหลัง passkey ถูกระงับ
+	ErrPasskeyCloned = errors.New("passkey has been disabled because it may be cloned")
+)
+
+const (
+	// PasskeySessionTTL อายุของ challenge ระหว่าง begin กับ finish
+	PasskeySessionTTL = 5 * time.Minute
+	// maxPasskeyNameLength ความยาวสูงสุดของชื่อ passkey
+	maxPasskeyNameLength = 64
+)
+
+// WebAuthnConfig ข้อมูล relying party ของ WebAuthn
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
+// LoadWebAuthnConfig อ่าน WEBAUTHN_RP_ID, WEBAUTHN_RP_DISPLAY_NAME และ WEBAUTHN_RP_ORIGINS (คั่นด้วย ,)
+func LoadWebAuthnConfig() WebAuthnConfig {
+	cfg := WebAuthnConfig{
+		RPID:          os.Getenv("WEBAUTHN_RP_ID"),
+		RPDisplayName: os.Getenv("WEBAUTHN_RP_DISPLAY_NAME"),
+	}
+	if cfg.RPID == "" {
+		cfg.RPID = "localhost"
+	}
+	if cfg.RPDisplayName == "" {
+		cfg.RPDisplayName = "CollP"
+	}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.RPOrigins = append(cfg.RPOrigins, origin)
+		}
+	}
+	if len(cfg.RPOrigins) == 0 {
+		cfg.RPOrigins = []string{"http://localhost:3000"}
+	}
+	return cfg
+}
+
+// PasskeyChallenge options ที่ส่งให้ navigator.credentials.create/get พร้อม session_token สำหรับขั้น finish
+type PasskeyChallenge struct {
+	SessionToken string      `json:"session_token"`
+	Options      interface{} `json:"options"`
+}
+
+// passkeySession สถานะของ ceremony ที่รอ authenticator ตอบกลับ
+type passkeySession struct {
+	// userID เจ้าของ registration (0 = login)
+	userID uint
+	data   webauthn.SessionData
+}
+
+// WebAuthnService interface สำหรับ passkey registration และ login
+// credential คือ JSON ของ PublicKeyCredential ที่ browser ส่งกลับมา
+type WebAuthnService interface {
+	BeginRegistration(userID uint) (*PasskeyChallenge, error)
+	FinishRegistration(userID uint, sessionToken, name string, credential []byte) (*models.WebAuthnCredential, error)
+	BeginLogin() (*PasskeyChallenge, error)
+	FinishLogin(sessionToken string, credential []byte, ip string) (*models.User, error)
+	ListCredentials(userID uint) ([]*models.WebAuthnCredential, error)
+	DeleteCredential(userID, id uint) error
+}
+
+// webAuthnService struct implements WebAuthnService interface
+type webAuthnService struct {
+	webauthn       *webauthn.WebAuthn
+	userRepo       repositories.UserRepository
+	credentialRepo repositories.WebAuthnCredentialRepository
+	identityRepo   repositories.UserIdentityRepository
+	audit          AuditService
+	sessions       *oneTimeStore[*passkeySession]
+}
+
+// NewWebAuthnService creates new passkey service instance
+func NewWebAuthnService(cfg WebAuthnConfig, userRepo repositories.UserRepository, credentialRepo repositories.WebAuthnCredentialRepository, identityRepo repositories.UserIdentityRepository, audit AuditService) (WebAuthnService, error) {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		// passkey ต้องเป็น discoverable credential และยืนยันตัวตนบนอุปกรณ์ (PIN/biometric)
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebAuthn configuration: %w", err)
+	}
+
+	return &webAuthnService{
+		webauthn:       w,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		identityRepo:   identityRepo,
+		audit:          audit,
+		sessions:       newOneTimeStore[*passkeySession](PasskeySessionTTL),
+	}, nil
+}
+
+// webAuthnUser ปรับ models.User ให้เป็น webauthn.User
+type webAuthnUser struct {
+	user        *models.User
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return webAuthnUserHandle(u.user.ID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// webAuthnUserHandle user handle ที่เก็บใน passkey คือ user ID แบบ 8 bytes
+func webAuthnUserHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// toWebAuthnCredential แปลง passkey ใน DB เป็น credential record ของ go-webauthn
+func toWebAuthnCredential(c *models.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	for _, transport := range strings.Split(c.Transports, ",") {
+		if transport != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              c.CredentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       c.AAGUID,
+			SignCount:    c.SignCount,
+			CloneWarning: c.CloneWarning,
+		},
+	}
+}
+
+// loadWebAuthnUser ดึง user พร้อม passkeys ทั้งหมด
+func (s *webAuthnService) loadWebAuthnUser(userID uint) (*webAuthnUser, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	stored, err := s.credentialRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		credentials = append(credentials, toWebAuthnCredential(c))
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// createSession เก็บ SessionData ไว้รอขั้น finish และคืน session_token
+func (s *webAuthnService) createSession(userID uint, data *webauthn.SessionData) string {
+	token := utils.GenerateRandomString(43)
+	s.sessions.Put(token, &passkeySession{userID: userID, data: *data})
+	return token
+}
+
+// takeSession ดึง session ของ ceremony ออกจาก store (ใช้ได้ครั้งเดียว)
+func (s *webAuthnService) takeSession(sessionToken string, userID uint) (*passkeySession, error) {
+	session, ok := s.sessions.Take(sessionToken)
+	if sessionToken == "" || !ok || session.userID != userID {
+		return nil, ErrInvalidPasskeySession
+	}
+	return session, nil
+}
+
+// BeginRegistration สร้าง options สำหรับลงทะเบียน passkey ใหม่ของ user ปัจจุบัน
+func (s *webAuthnService) BeginRegistration(userID uint) (*PasskeyChallenge, error) {
+	user, err := s.loadWebAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ไม่ให้ลงทะเบียน authenticator เดิมซ้ำ
+	exclusions := webauthn.Credentials(user.credentials).CredentialDescriptors()
+	creation, session, err := s.webauthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin passkey registration: %w", err)
+	}
+
+	return &PasskeyChallenge{
+		SessionToken: s.createSession(userID, session),
+		Options:      creation,
+	}, nil
+}
+
+// FinishRegistration ตรวจสอบ attestation แล้วบันทึก passkey
+func (s *webAuthnService) FinishRegistration(userID uint, sessionToken, name string, credential []byte) (*models.WebAuthnCredential, error) {
+	session, err := s.takeSession(sessionToken, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadWebAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+	created, err := s.webauthn.CreateCredential(user, session.data, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if len([]rune(name)) > maxPasskeyNameLength {
+		name = string([]rune(name)[:maxPasskeyNameLength])
+	}
+
+	transports := make([]string, 0, len(created.Transport))
+	for _, transport := range created.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	record := &models.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    created.ID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		AAGUID:          created.Authenticator.AAGUID,
+		Transports:      strings.Join(transports, ","),
+		SignCount:       created.Authenticator.SignCount,
+		BackupEligible:  created.Flags.BackupEligible,
+		BackupState:     created.Flags.BackupState,
+	}
+	if err := s.credentialRepo.Create(record); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditPasskeyAdded,
+		ActorID: &userID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("passkey %d (%s)", record.ID, record.Name),
+	})
+	return record, nil
+}
+
+// BeginLogin สร้าง options สำหรับ login ด้วย passkey (ไม่ต้องรู้ email ก่อน)
+func (s *webAuthnService) BeginLogin() (*PasskeyChallenge, error) {
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin passkey login: %w", err)
+	}
+
+	return &PasskeyChallenge{
+		SessionToken: s.createSession(0, session),
+		Options:      assertion,
+	}, nil
+}
+
+// FinishLogin ตรวจสอบ assertion แล้วคืน user เจ้าของ passkey
+// sign counter ที่ไม่เพิ่มขึ้นจะระงับ passkey นั้นและบันทึก audit log
+func (s *webAuthnService) FinishLogin(sessionToken string, credential []byte, ip string) (*models.User, error) {
+	session, err := s.takeSession(sessionToken, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+
+	// handler หา passkey และเจ้าของจาก credential ID กับ user handle ที่ authenticator ส่งมา
+	var stored *models.WebAuthnCredential
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		c, err := s.credentialRepo.GetByCredentialID(rawID)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(userHandle, webAuthnUserHandle(c.UserID)) {
+			return nil, ErrPasskeyVerificationFailed
+		}
+		user, err := s.loadWebAuthnUser(c.UserID)
+		if err != nil {
+			return nil, err
+		}
+		stored = c
+		return user, nil
+	}
+
+	owner, validated, err := s.webauthn.ValidatePasskeyLogin(handler, session.data, parsed)
+	if err != nil {
+		if errors.Is(err, ErrUserInactive) {
+			return nil, ErrUserInactive
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+	account := owner.(*webAuthnUser)
+
+	if stored.CloneWarning {
+		return nil, ErrPasskeyCloned
+	}
+	if !validated.Authenticator.CloneWarning {
+		recorded, err := s.credentialRepo.RecordUse(stored.ID, validated.Authenticator.SignCount, validated.Flags.BackupState)
+		if err != nil {
+			return nil, err
+		}
+		if recorded {
+			return account.user, nil
+		}
+	}
+
+	if err := s.credentialRepo.MarkCloneWarning(stored.ID); err != nil {
+		return nil, err
+	}
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditPasskeyCloned,
+		UserID:  &stored.UserID,
+		IP:      ip,
+		Details: fmt.Sprintf("passkey %d sign count %d, stored %d", stored.ID, parsed.Response.AuthenticatorData.Counter, stored.SignCount),
+	})
+	return nil, ErrPasskeyCloned
+}
+
+// ListCredentials ดึง passkeys ของ user
+func (s *webAuthnService) ListCredentials(userID uint) ([]*models.WebAuthnCredential, error) {
+	return s.credentialRepo.GetByUserID(userID)
+}
+
+// DeleteCredential ลบ passkey ของ user
+func (s *webAuthnService) DeleteCredential(userID, id uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	credentials, err := s.credentialRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	owned := false
+	for _, credential := range credentials {
+		if credential.ID == id {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return repositories.ErrWebAuthnCredentialNotFound
+	}
+
+	// ห้ามลบ passkey ถ้าเป็นวิธี login สุดท้ายที่เหลืออยู่
+	methods, err := countLoginMethods(user, s.identityRepo, s.credentialRepo)
+	if err != nil {
+		return err
+	}
+	if methods <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.credentialRepo.Delete(userID, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(&models.AuditLog{
+		Action:  models.AuditPasskeyRemoved,
+		ActorID: &userID,
+		UserID:  &userID,
+		Details: fmt.Sprintf("passkey %d", id),
+	})
+	return nil
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"collp-backend/models"
+	"collp-backend/repositories"
+
+	"github.com/descope/virtualwebauthn"
+)
+
+// fakePasskeyUsers user repository ที่มีเฉพาะ GetByID
+type fakePasskeyUsers struct {
+	repositories.UserRepository
+	users map[uint]*models.User
+}
+
+func (r *fakePasskeyUsers) GetByID(id uint) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// fakeCredentials passkey repository ในหน่วยความจำที่ใช้เงื่อนไขเดียวกับ RecordUse ใน DB
+type fakeCredentials struct {
+	list []*models.WebAuthnCredential
+	// beforeRecordUse จำลอง login อื่นที่อัพเดท sign counter ก่อน (เช่น authenticator ที่ถูก clone)
+	beforeRecordUse func()
+}
+
+func (r *fakeCredentials) Create(credential *models.WebAuthnCredential) error {
+	credential.ID = uint(len(r.list) + 1)
+	copied := *credential
+	r.list = append(r.list, &copied)
+	return nil
+}
+
+func (r *fakeCredentials) GetByUserID(userID uint) ([]*models.WebAuthnCredential, error) {
+	var credentials []*models.WebAuthnCredential
+	for _, credential := range r.list {
+		if credential.UserID == userID {
+			copied := *credential
+			credentials = append(credentials, &copied)
+		}
+	}
+	return credentials, nil
+}
+
+func (r *fakeCredentials) GetByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error) {
+	for _, credential := range r.list {
+		if bytes.Equal(credential.CredentialID, credentialID) {
+			copied := *credential
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrWebAuthnCredentialNotFound
+}
+
+func (r *fakeCredentials) RecordUse(id uint, signCount uint32, backupState bool) (bool, error) {
+	if r.beforeRecordUse != nil {
+		r.beforeRecordUse()
+	}
+	credential := r.list[id-1]
+	if credential.CloneWarning || !(credential.SignCount < signCount || (credential.SignCount == 0 && signCount == 0)) {
+		return false, nil
+	}
+	credential.SignCount = signCount
+	credential.BackupState = backupState
+	return true, nil
+}
+
+func (r *fakeCredentials) MarkCloneWarning(id uint) error {
+	r.list[id-1].CloneWarning = true
+	return nil
+}
+
+func (r *fakeCredentials) Delete(userID, id uint) error {
+	return nil
+}
+
+// fakeAudit เก็บ actions ที่บันทึก
+type fakeAudit struct {
+	AuditService
+	actions []string
+}
+
+func (a *fakeAudit) Record(entry *models.AuditLog) {
+	a.actions = append(a.actions, entry.Action)
+}
+
+// passkeyTest ต่อ WebAuthnService เข้ากับ software authenticator ของ virtualwebauthn
+type passkeyTest struct {
+	t             *testing.T
+	service       WebAuthnService
+	users         *fakePasskeyUsers
+	credentials   *fakeCredentials
+	audit         *fakeAudit
+	rp            virtualwebauthn.RelyingParty
+	authenticator virtualwebauthn.Authenticator
+	credential    virtualwebauthn.Credential
+}
+
+func newPasskeyTest(t *testing.T) *passkeyTest {
+	t.Helper()
+	users := &fakePasskeyUsers{users: map[uint]*models.User{
+		7: {ID: 7, Email: "user@example.com", Name: "User", IsActive: true},
+	}}
+	credentials := &fakeCredentials{}
+	audit := &fakeAudit{}
+
+	service, err := NewWebAuthnService(WebAuthnConfig{
+		RPID:          "example.com",
+		RPDisplayName: "CollP",
+		RPOrigins:     []string{"https://app.example.com"},
+	}, users, credentials, nil, audit)
+	if err != nil {
+		t.Fatalf("NewWebAuthnService error: %v", err)
+	}
+
+	return &passkeyTest{
+		t:             t,
+		service:       service,
+		users:         users,
+		credentials:   credentials,
+		audit:         audit,
+		rp:            virtualwebauthn.RelyingParty{Name: "CollP", ID: "example.com", Origin: "https://app.example.com"},
+		authenticator: virtualwebauthn.NewAuthenticator(),
+		credential:    virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2),
+	}
+}
+
+// register ลงทะเบียน passkey ของ software authenticator ให้ user
+func (p *passkeyTest) register(userID uint) (*models.WebAuthnCredential, error) {
+	p.t.Helper()
+	challenge, err := p.service.BeginRegistration(userID)
+	if err != nil {
+		p.t.Fatalf("BeginRegistration error: %v", err)
+	}
+	options, err := json.Marshal(challenge.Options)
+	if err != nil {
+		p.t.Fatalf("failed to marshal creation options: %v", err)
+	}
+	attestation, err := virtualwebauthn.ParseAttestationOptions(string(options))
+	if err != nil {
+		p.t.Fatalf("ParseAttestationOptions error: %v", err)
+	}
+
+	response := virtualwebauthn.CreateAttestationResponse(p.rp, p.authenticator, p.credential, *attestation)
+	record, err := p.service.FinishRegistration(userID, challenge.SessionToken, " Laptop ", []byte(response))
+	if err == nil {
+		p.authenticator.Options.UserHandle = []byte(attestation.UserID)
+		p.authenticator.AddCredential(p.credential)
+	}
+	return record, err
+}
+
+// login ใช้ passkey ที่ลงทะเบียนแล้ว login ด้วย sign counter ที่กำหนด
+func (p *passkeyTest) login(counter uint32) (*models.User, error) {
+	p.t.Helper()
+	challenge, err := p.service.BeginLogin()
+	if err != nil {
+		p.t.Fatalf("BeginLogin error: %v", err)
+	}
+	options, err := json.Marshal(challenge.Options)
+	if err != nil {
+		p.t.Fatalf("failed to marshal request options: %v", err)
+	}
+	assertion, err := virtualwebauthn.ParseAssertionOptions(string(options))
+	if err != nil {
+		p.t.Fatalf("ParseAssertionOptions error: %v", err)
+	}
+
+	p.credential.Counter = counter
+	response := virtualwebauthn.CreateAssertionResponse(p.rp, p.authenticator, p.credential, *assertion)
+	return p.service.FinishLogin(challenge.SessionToken, []byte(response), "192.0.2.1")
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	p := newPasskeyTest(t)
+
+	record, err := p.register(7)
+	if err != nil {
+		t.Fatalf("FinishRegistration error: %v", err)
+	}
+	if record.UserID != 7 || record.Name != "Laptop" {
+		t.Errorf("registered passkey = user %d name %q, want user 7 name %q", record.UserID, record.Name, "Laptop")
+	}
+	if !bytes.Equal(record.CredentialID, p.credential.ID) || len(record.PublicKey) == 0 {
+		t.Error("registered passkey does not store the authenticator's credential ID and public key")
+	}
+
+	for _, counter := range []uint32{1, 2, 10} {
+		user, err := p.login(counter)
+		if err != nil {
+			t.Fatalf("login with counter %d: %v", counter, err)
+		}
+		if user.ID != 7 {
+			t.Fatalf("login with counter %d: user = %d, want 7", counter, user.ID)
+		}
+		if got := p.credentials.list[0].SignCount; got != counter {
+			t.Errorf("login with counter %d: stored sign count = %d", counter, got)
+		}
+	}
+
+	want := []string{models.AuditPasskeyAdded}
+	if len(p.audit.actions) != len(want) || p.audit.actions[0] != want[0] {
+		t.Errorf("audit actions = %v, want %v", p.audit.actions, want)
+	}
+}
+
+func TestPasskeyRegistrationSessionIsSingleUse(t *testing.T) {
+	p := newPasskeyTest(t)
+
+	challenge, err := p.service.BeginRegistration(7)
+	if err != nil {
+		t.Fatalf("BeginRegistration error: %v", err)
+	}
+	options, _ := json.Marshal(challenge.Options)
+	attestation, err := virtualwebauthn.ParseAttestationOptions(string(options))
+	if err != nil {
+		t.Fatalf("ParseAttestationOptions error: %v", err)
+	}
+	response := []byte(virtualwebauthn.CreateAttestationResponse(p.rp, p.authenticator, p.credential, *attestation))
+
+	tests := []struct {
+		name    string
+		userID  uint
+		token   string
+		wantErr error
+	}{
+		{name: "session of another user", userID: 8, token: challenge.SessionToken, wantErr: ErrInvalidPasskeySession},
+		{name: "unknown session", userID: 7, token: "unknown", wantErr: ErrInvalidPasskeySession},
+	}
+	for _, tt := range tests {
+		if _, err := p.service.FinishRegistration(tt.userID, tt.token, "", response); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// ใช้ session ของ user อื่นไปแล้ว session นั้นหมดสิทธิ์
+	if _, err := p.service.FinishRegistration(7, challenge.SessionToken, "", response); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("reused session: error = %v, want %v", err, ErrInvalidPasskeySession)
+	}
+}
+
+func TestPasskeyRejectsWrongOrigin(t *testing.T) {
+	p := newPasskeyTest(t)
+	p.rp.Origin = "https://evil.example.net"
+
+	if _, err := p.register(7); !errors.Is(err, ErrPasskeyVerificationFailed) {
+		t.Fatalf("register from wrong origin: error = %v, want %v", err, ErrPasskeyVerificationFailed)
+	}
+	if len(p.credentials.list) != 0 {
+		t.Error("passkey stored after failed verification")
+	}
+}
+
+func TestPasskeyLoginInactiveUser(t *testing.T) {
+	p := newPasskeyTest(t)
+	if _, err := p.register(7); err != nil {
+		t.Fatalf("FinishRegistration error: %v", err)
+	}
+
+	p.users.users[7].IsActive = false
+	if _, err := p.login(1); !errors.Is(err, ErrUserInactive) {
+		t.Errorf("login of inactive user: error = %v, want %v", err, ErrUserInactive)
+	}
+}
+
+func TestPasskeyCloneDetection(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup เตรียมสถานะก่อน login ด้วย counter 5
+		setup func(p *passkeyTest)
+	}{
+		{
+			// counter ถอยหลังจากค่าที่เก็บไว้ go-webauthn ตั้ง CloneWarning เอง
+			name: "counter lower than stored",
+			setup: func(p *passkeyTest) {
+				if _, err := p.login(10); err != nil {
+					t.Fatalf("first login: %v", err)
+				}
+			},
+		},
+		{
+			// login อื่นอัพเดท counter ระหว่างตรวจสอบ RecordUse จึงคืน false
+			name: "counter raced by another login",
+			setup: func(p *passkeyTest) {
+				p.credentials.beforeRecordUse = func() {
+					p.credentials.list[0].SignCount = 6
+					p.credentials.beforeRecordUse = nil
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPasskeyTest(t)
+			if _, err := p.register(7); err != nil {
+				t.Fatalf("FinishRegistration error: %v", err)
+			}
+			tt.setup(p)
+
+			if _, err := p.login(5); !errors.Is(err, ErrPasskeyCloned) {
+				t.Fatalf("login with cloned counter: error = %v, want %v", err, ErrPasskeyCloned)
+			}
+			if !p.credentials.list[0].CloneWarning {
+				t.Error("passkey not flagged with clone warning")
+			}
+			if last := p.audit.actions[len(p.audit.actions)-1]; last != models.AuditPasskeyCloned {
+				t.Errorf("last audit action = %q, want %q", last, models.AuditPasskeyCloned)
+			}
+
+			// passkey ที่ถูกระงับใช้ไม่ได้อีกแม้ counter จะเพิ่มขึ้น
+			if _, err := p.login(100); !errors.Is(err, ErrPasskeyCloned) {
+				t.Errorf("login after clone warning: error = %v, want %v", err, ErrPasskeyCloned)
+			}
+		})
+	}
+}
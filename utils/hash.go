@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -25,3 +28,21 @@ func GenerateRandomString(length int) string {
 	rand.Read(bytes)
 	return base64.URLEncoding.EncodeToString(bytes)[:length]
 }
+
+// HashToken hashes a high-entropy token (refresh tokens, codes) with SHA-256 for storage
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignToken returns the HMAC-SHA256 of message under key, base64url encoded without padding
+func SignToken(key []byte, message string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyTokenSignature checks a signature made by SignToken in constant time
+func VerifyTokenSignature(key []byte, message, signature string) bool {
+	return hmac.Equal([]byte(SignToken(key, message)), []byte(signature))
+}
@@ -1,40 +1,103 @@
 package utils
 
 import (
-	"crypto/rsa"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AccessTokenTTL is the lifetime of access tokens issued by GenerateJWT
+const AccessTokenTTL = 2 * time.Hour
+
+// Token types carried in JWTClaims.TokenType
+const (
+	// TokenTypePersonalAccess marks claims built from a personal access token
+	TokenTypePersonalAccess = "pat"
+	// TokenTypeClient marks access tokens issued to OAuth clients (client_credentials grant)
+	TokenTypeClient = "client"
+)
+
+func init() {
+	// iat and exp carry microseconds so a revocation cutoff (see
+	// TokenRevocationService.RevokeAllForUser) does not also reject tokens
+	// issued later in the same second
+	jwt.TimePrecision = time.Microsecond
+}
+
 // JWTClaims represents the claims in JWT token
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID        uint     `json:"user_id"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Role          string   `json:"role,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
+	// TokenType is empty for access tokens issued at login
+	TokenType string `json:"token_type,omitempty"`
+	// ClientID is the OAuth client of a service caller; UserID is 0 for these tokens
+	ClientID string `json:"client_id,omitempty"`
+	// SessionID is the login session (refresh token family) the token was issued for
+	SessionID uint `json:"sid,omitempty"`
+	// OrgID is the organization the caller is acting in; switching it issues a new token
+	OrgID uint `json:"org_id,omitempty"`
+	// Act is the real caller when an admin impersonates UserID (RFC 8693 section 4.1)
+	Act *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a JWT token for user
-func GenerateJWT(userID uint, email string, privateKey *rsa.PrivateKey) (string, error) {
-	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   email,
-		},
+// ActorClaim identifies the admin acting on behalf of the token's user
+type ActorClaim struct {
+	Subject string `json:"sub"`
+	UserID  uint   `json:"user_id"`
+}
+
+// HasPermission reports whether the token grants the given permission
+func (c *JWTClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsServiceClient reports whether the caller is an OAuth client rather than a user
+func (c *JWTClaims) IsServiceClient() bool {
+	return c.TokenType == TokenTypeClient
+}
+
+// IsImpersonated reports whether an admin is acting as the token's user
+func (c *JWTClaims) IsImpersonated() bool {
+	return c.Act != nil
+}
+
+// IsPersonalAccessToken reports whether the caller authenticated with a personal access token
+func (c *JWTClaims) IsPersonalAccessToken() bool {
+	return c.TokenType == TokenTypePersonalAccess
+}
+
+// GenerateJWT generates a JWT token for user signed with the active key.
+// Expiry, issued-at, jti and subject are filled in when not set.
+func GenerateJWT(claims JWTClaims, keys *KeyManager) (string, error) {
+	now := time.Now()
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(AccessTokenTTL))
+	}
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if claims.Subject == "" {
+		claims.Subject = claims.Email
+	}
+	if claims.ID == "" {
+		claims.ID = GenerateRandomString(22)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(privateKey)
+	return keys.Sign(claims)
 }
 
-// ValidateJWT validates a JWT token
-func ValidateJWT(tokenString string, publicKey *rsa.PublicKey) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return publicKey, nil
-	})
+// ValidateJWT validates a JWT token against any non-retired key
+func ValidateJWT(tokenString string, keys *KeyManager) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keys.Keyfunc)
 
 	if err != nil {
 		return nil, err
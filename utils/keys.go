@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyStatus describes how a signing key may be used
+type KeyStatus string
+
+const (
+	// KeyStatusActive signs new tokens and verifies existing ones
+	KeyStatusActive KeyStatus = "active"
+	// KeyStatusVerify only verifies tokens (previous key, or next key published ahead of rotation)
+	KeyStatusVerify KeyStatus = "verify"
+	// KeyStatusRetired is neither used nor published
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+// keyManifestFile lists the active and retired kids inside the keys directory
+const keyManifestFile = "keys.json"
+
+// SigningKey is an RSA key identified by its kid
+type SigningKey struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+	Status     KeyStatus
+}
+
+// keyManifest is the format of keys.json
+type keyManifest struct {
+	Active  string   `json:"active"`
+	Retired []string `json:"retired"`
+}
+
+// JWK is a public RSA key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyManager holds the RSA keys used to sign and verify JWTs.
+// Keys are loaded from a directory of <kid>.pem files plus keys.json, or from a
+// single PEM file when no directory is configured. Reload swaps keys at runtime.
+type KeyManager struct {
+	mu       sync.RWMutex
+	dir      string
+	fallback string
+	keys     map[string]*SigningKey
+	active   *SigningKey
+}
+
+// LoadKeyManager loads keys from dir, falling back to a single PEM file when dir is empty
+func LoadKeyManager(dir, fallbackFile string) (*KeyManager, error) {
+	km := &KeyManager{
+		dir:      dir,
+		fallback: fallbackFile,
+	}
+	if err := km.Reload(); err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+// Reload re-reads keys from disk; on error the current keys stay in use
+func (km *KeyManager) Reload() error {
+	var (
+		keys   map[string]*SigningKey
+		active *SigningKey
+		err    error
+	)
+	if km.dir != "" {
+		keys, active, err = loadKeyDir(km.dir)
+	} else {
+		keys, active, err = loadKeyFile(km.fallback)
+	}
+	if err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	km.keys = keys
+	km.active = active
+	km.mu.Unlock()
+	return nil
+}
+
+// ActiveKeyID returns the kid used to sign new tokens
+func (km *KeyManager) ActiveKeyID() string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return km.active.ID
+}
+
+// Sign signs claims with the active key using RS256 and sets the kid header
+func (km *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	km.mu.RLock()
+	active := km.active
+	km.mu.RUnlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = active.ID
+	return token.SignedString(active.PrivateKey)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+// Tokens without kid (issued before key rotation existed) use the active key.
+func (km *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return &km.active.PrivateKey.PublicKey, nil
+	}
+
+	key, ok := km.keys[kid]
+	if !ok || key.Status == KeyStatusRetired {
+		return nil, fmt.Errorf("unknown or retired signing key: %s", kid)
+	}
+	return &key.PrivateKey.PublicKey, nil
+}
+
+// JWKS returns the public keys of all non-retired keys
+func (km *KeyManager) JWKS() JWKS {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range km.keys {
+		if key.Status == KeyStatusRetired {
+			continue
+		}
+		set.Keys = append(set.Keys, publicJWK(key.ID, &key.PrivateKey.PublicKey))
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// loadKeyDir loads every <kid>.pem in dir and applies keys.json
+func loadKeyDir(dir string) (map[string]*SigningKey, *SigningKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list keys in %s: %w", dir, err)
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	keys := make(map[string]*SigningKey, len(files))
+	for _, file := range files {
+		privateKey, err := readRSAPrivateKey(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		keys[kid] = &SigningKey{ID: kid, PrivateKey: privateKey, Status: KeyStatusVerify}
+	}
+
+	var manifest keyManifest
+	data, err := os.ReadFile(filepath.Join(dir, keyManifestFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", keyManifestFile, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && len(keys) == 1:
+		for kid := range keys {
+			manifest.Active = kid
+		}
+	default:
+		return nil, nil, fmt.Errorf("failed to read %s: %w", keyManifestFile, err)
+	}
+
+	for _, kid := range manifest.Retired {
+		if key, ok := keys[kid]; ok {
+			key.Status = KeyStatusRetired
+		}
+	}
+
+	active, ok := keys[manifest.Active]
+	if !ok || active.Status == KeyStatusRetired {
+		return nil, nil, fmt.Errorf("active key %q not found or retired in %s", manifest.Active, dir)
+	}
+	active.Status = KeyStatusActive
+
+	return keys, active, nil
+}
+
+// loadKeyFile loads a single PEM file as the active key, using its JWK thumbprint as kid
+func loadKeyFile(file string) (map[string]*SigningKey, *SigningKey, error) {
+	privateKey, err := readRSAPrivateKey(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kid := keyThumbprint(&privateKey.PublicKey)
+	active := &SigningKey{ID: kid, PrivateKey: privateKey, Status: KeyStatusActive}
+	return map[string]*SigningKey{kid: active}, active, nil
+}
+
+func readRSAPrivateKey(file string) (*rsa.PrivateKey, error) {
+	keyData, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSA private key %s: %w", file, err)
+	}
+	return privateKey, nil
+}
+
+func publicJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// keyThumbprint computes the RFC 7638 JWK thumbprint of an RSA public key
+func keyThumbprint(key *rsa.PublicKey) string {
+	jwk := publicJWK("", key)
+	canonical := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
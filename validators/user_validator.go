@@ -12,12 +12,27 @@ type UserRegistrationRequest struct {
 	Password string `json:"password" binding:"required"`
 	Phone    string `json:"phone,omitempty"`
 	Address  string `json:"address,omitempty"`
+	// InvitationToken token จากลิงก์คำเชิญเข้า organization (ถ้ามี)
+	InvitationToken string `json:"invitation_token,omitempty"`
 }
 
 // UserLoginRequest represents user login data
 type UserLoginRequest struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// InvitationToken token จากลิงก์คำเชิญเข้า organization (ถ้ามี)
+	InvitationToken string `json:"invitation_token,omitempty"`
+}
+
+// PasswordForgotRequest represents a password reset request
+type PasswordForgotRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// PasswordResetRequest represents a new password set with a reset token
+type PasswordResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
 
 // ValidateUserRegistration validates user registration data
@@ -61,3 +76,33 @@ func ValidateUserLogin(req UserLoginRequest) error {
 
 	return nil
 }
+
+// ValidatePasswordForgot validates a password reset request
+func ValidatePasswordForgot(req PasswordForgotRequest) error {
+	if utils.IsEmpty(req.Email) {
+		return errors.New("email is required")
+	}
+
+	if !utils.IsValidEmail(req.Email) {
+		return errors.New("invalid email format")
+	}
+
+	return nil
+}
+
+// ValidatePasswordReset validates a new password set with a reset token
+func ValidatePasswordReset(req PasswordResetRequest) error {
+	if utils.IsEmpty(req.Token) {
+		return errors.New("token is required")
+	}
+
+	if utils.IsEmpty(req.Password) {
+		return errors.New("password is required")
+	}
+
+	if !utils.IsValidPassword(req.Password) {
+		return errors.New("password must be at least 8 characters long and contain uppercase, lowercase, and number")
+	}
+
+	return nil
+}